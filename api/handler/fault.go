@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lits-06/vcs-sms/pkg/logger"
+	"github.com/lits-06/vcs-sms/usecases/server"
+)
+
+type FaultHandler struct {
+	service server.FaultInjector
+	logger  logger.Logger
+}
+
+func NewFaultHandler(service server.FaultInjector, logger logger.Logger) *FaultHandler {
+	log := logger.With("handler", "fault")
+
+	return &FaultHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+func (h *FaultHandler) InjectFault(c *gin.Context) {
+	var req server.InjectFaultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	fault, err := h.service.InjectFault(c.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to inject fault", "error", err)
+		if errors.Is(err, server.ErrInvalidFault) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to inject fault"})
+		return
+	}
+	h.logger.Warn("Fault injected", "fault_id", fault.ID, "kind", fault.Kind,
+		"server_ids", fault.ServerIDs, "percentage", fault.Percentage)
+	c.JSON(http.StatusCreated, fault)
+}
+
+func (h *FaultHandler) ListFaults(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"faults": h.service.ListFaults(c.Request.Context())})
+}
+
+func (h *FaultHandler) ClearFault(c *gin.Context) {
+	faultID := c.Param("id")
+	if err := h.service.ClearFault(c.Request.Context(), faultID); err != nil {
+		h.logger.Error("Failed to clear fault", "fault_id", faultID, "error", err)
+		if errors.Is(err, server.ErrFaultNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Fault not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear fault"})
+		return
+	}
+	h.logger.Info("Fault cleared", "fault_id", faultID)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *FaultHandler) ClearFaults(c *gin.Context) {
+	h.service.ClearFaults(c.Request.Context())
+	h.logger.Info("All faults cleared")
+	c.Status(http.StatusNoContent)
+}
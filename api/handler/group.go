@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lits-06/vcs-sms/pkg/logger"
+	"github.com/lits-06/vcs-sms/usecases/server"
+)
+
+type GroupHandler struct {
+	service server.GroupService
+	logger  logger.Logger
+}
+
+func NewGroupHandler(service server.GroupService, logger logger.Logger) *GroupHandler {
+	log := logger.With("handler", "group")
+
+	return &GroupHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var req server.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	group, err := h.service.CreateGroup(c.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to create group", "name", req.Name, "error", err)
+		h.groupError(c, err, "Failed to create group")
+		return
+	}
+	h.logger.Info("Group created", "group_id", group.ID, "name", group.Name, "members", len(group.ServerIDs))
+	c.JSON(http.StatusCreated, group)
+}
+
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	groups, err := h.service.ListGroups(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list groups", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list groups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	groupID := c.Param("id")
+	group, err := h.service.GetGroup(c.Request.Context(), groupID)
+	if err != nil {
+		h.logger.Error("Failed to get group", "group_id", groupID, "error", err)
+		h.groupError(c, err, "Failed to get group")
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	var req server.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.ID = c.Param("id")
+
+	group, err := h.service.UpdateGroup(c.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to update group", "group_id", req.ID, "error", err)
+		h.groupError(c, err, "Failed to update group")
+		return
+	}
+	h.logger.Info("Group updated", "group_id", group.ID)
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	groupID := c.Param("id")
+	if err := h.service.DeleteGroup(c.Request.Context(), groupID); err != nil {
+		h.logger.Error("Failed to delete group", "group_id", groupID, "error", err)
+		h.groupError(c, err, "Failed to delete group")
+		return
+	}
+	h.logger.Info("Group deleted", "group_id", groupID)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *GroupHandler) AddGroupMembers(c *gin.Context) {
+	groupID := c.Param("id")
+	var req server.GroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	group, err := h.service.AddGroupMembers(c.Request.Context(), groupID, req)
+	if err != nil {
+		h.logger.Error("Failed to add group members", "group_id", groupID, "error", err)
+		h.groupError(c, err, "Failed to add group members")
+		return
+	}
+	h.logger.Info("Group members added", "group_id", groupID, "server_ids", req.ServerIDs)
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *GroupHandler) RemoveGroupMembers(c *gin.Context) {
+	groupID := c.Param("id")
+	var req server.GroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	group, err := h.service.RemoveGroupMembers(c.Request.Context(), groupID, req)
+	if err != nil {
+		h.logger.Error("Failed to remove group members", "group_id", groupID, "error", err)
+		h.groupError(c, err, "Failed to remove group members")
+		return
+	}
+	h.logger.Info("Group members removed", "group_id", groupID, "server_ids", req.ServerIDs)
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *GroupHandler) StartGroup(c *gin.Context) {
+	h.runGroupAction(c, h.service.StartGroup)
+}
+
+func (h *GroupHandler) StopGroup(c *gin.Context) {
+	h.runGroupAction(c, h.service.StopGroup)
+}
+
+func (h *GroupHandler) ExportGroup(c *gin.Context) {
+	groupID := c.Param("id")
+	writeExport(c, h.logger, "group", func(ctx context.Context, req server.ExportServerRequest, w io.Writer) error {
+		return h.service.ExportGroup(ctx, groupID, req, w)
+	})
+}
+
+// runGroupAction responds with the result of every member, with 207 Multi-Status when some of them failed
+func (h *GroupHandler) runGroupAction(c *gin.Context, action func(ctx context.Context, groupID string) (*server.GroupActionResponse, error)) {
+	groupID := c.Param("id")
+	response, err := action(c.Request.Context(), groupID)
+	if err != nil {
+		h.logger.Error("Failed to run group action", "group_id", groupID, "error", err)
+		h.groupError(c, err, "Failed to run group action")
+		return
+	}
+
+	h.logger.Info("Group action finished", "group_id", groupID, "action", response.Action,
+		"succeeded", response.SucceededCount, "failed", response.FailedCount)
+	if response.FailedCount > 0 {
+		c.JSON(http.StatusMultiStatus, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// groupError responds to a failed group request, with message for unexpected errors
+func (h *GroupHandler) groupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, server.ErrGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+	case errors.Is(err, server.ErrGroupExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, server.ErrInvalidGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lits-06/vcs-sms/pkg/logger"
+	"github.com/lits-06/vcs-sms/usecases/server"
+)
+
+type ImportJobHandler struct {
+	service server.ImportJobService
+	logger  logger.Logger
+}
+
+func NewImportJobHandler(service server.ImportJobService, logger logger.Logger) *ImportJobHandler {
+	log := logger.With("handler", "import_job")
+
+	return &ImportJobHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+func (h *ImportJobHandler) GetImportJob(c *gin.Context) {
+	jobID := c.Param("id")
+
+	job, err := h.service.GetImportJob(c.Request.Context(), jobID)
+	if err != nil {
+		h.logger.Error("Failed to get import job", "job_id", jobID, "error", err)
+		if errors.Is(err, server.ErrImportJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get import job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *ImportJobHandler) CancelImportJob(c *gin.Context) {
+	jobID := c.Param("id")
+
+	job, err := h.service.CancelImportJob(c.Request.Context(), jobID)
+	if err != nil {
+		h.logger.Error("Failed to cancel import job", "job_id", jobID, "error", err)
+		switch {
+		case errors.Is(err, server.ErrImportJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, server.ErrImportJobFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel import job"})
+		}
+		return
+	}
+
+	h.logger.Info("Import job cancelled", "job_id", jobID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *ImportJobHandler) GetImportJobReport(c *gin.Context) {
+	jobID := c.Param("id")
+
+	report, err := h.service.GetImportJobReport(c.Request.Context(), jobID)
+	if err != nil {
+		h.logger.Error("Failed to get import job report", "job_id", jobID, "error", err)
+		if errors.Is(err, server.ErrImportJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get import job report"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import_report_%s.xlsx"`, jobID))
+	c.Data(http.StatusOK, server.FormatXLSX.ContentType(), report)
+}
@@ -1,8 +1,16 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lits-06/vcs-sms/pkg/logger"
@@ -11,14 +19,16 @@ import (
 
 type ServerHandler struct {
 	service server.UseCase
+	imports server.ImportJobService
 	logger  logger.Logger
 }
 
-func NewServerHandler(service server.UseCase, logger logger.Logger) *ServerHandler {
+func NewServerHandler(service server.UseCase, imports server.ImportJobService, logger logger.Logger) *ServerHandler {
 	log := logger.With("handler", "server")
 
 	return &ServerHandler{
 		service: service,
+		imports: imports,
 		logger:  log,
 	}
 }
@@ -52,6 +62,11 @@ func (h *ServerHandler) ViewServer(c *gin.Context) {
 	response, err := h.service.ViewServer(c.Request.Context(), req)
 	if err != nil {
 		h.logger.Error("Failed to view server", "error", err)
+		if errors.Is(err, server.ErrInvalidFilter) || errors.Is(err, server.ErrInvalidSort) ||
+			errors.Is(err, server.ErrInvalidFieldset) {
+			c.JSON(http.StatusBadRequest, filterError(err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to view server"})
 		return
 	}
@@ -59,6 +74,49 @@ func (h *ServerHandler) ViewServer(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *ServerHandler) SearchServers(c *gin.Context) {
+	var req server.SearchServerRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Error("Failed to bind query", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	response, err := h.service.SearchServers(c.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to search servers", "query", req.Query, "error", err)
+		if errors.Is(err, server.ErrInvalidSearch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search servers"})
+		return
+	}
+	h.logger.Info("Servers searched successfully", "query", req.Query, "results", len(response.Results))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ServerHandler) ServerStats(c *gin.Context) {
+	var req server.ServerStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Error("Failed to bind query", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	stats, err := h.service.ServerStats(c.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to compute server stats", "error", err)
+		if errors.Is(err, server.ErrInvalidFilter) {
+			c.JSON(http.StatusBadRequest, filterError(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute server stats"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
 func (h *ServerHandler) UpdateServer(c *gin.Context) {
 	var req server.UpdateServerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -87,7 +145,30 @@ func (h *ServerHandler) DeleteServer(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-func (h *ServerHandler) ImportServersFromExcel(c *gin.Context) {
+func (h *ServerHandler) ImportServers(c *gin.Context) {
+	var opts server.ImportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		h.logger.Error("Failed to bind query", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	mode, err := server.ParseImportMode(string(opts.Mode))
+	if err != nil {
+		h.logger.Error("Invalid import mode", "mode", opts.Mode)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts.Mode = mode
+
+	// Imports run synchronously unless async=true, which submits a background job
+	async, err := strconv.ParseBool(c.DefaultQuery("async", "false"))
+	if err != nil {
+		h.logger.Error("Invalid async flag", "async", c.Query("async"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "async must be true or false"})
+		return
+	}
+
 	// Get file from form
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
@@ -96,10 +177,11 @@ func (h *ServerHandler) ImportServersFromExcel(c *gin.Context) {
 		return
 	}
 
-	// Validate file type
-	if !isValidExcelFile(fileHeader.Filename) {
-		h.logger.Error("Invalid file type", "filename", fileHeader.Filename)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only Excel files (.xlsx, .xls) are allowed"})
+	// Detect file type from extension and MIME type
+	format, err := server.DetectImportFormat(fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
+	if err != nil {
+		h.logger.Error("Invalid file type", "filename", fileHeader.Filename, "content_type", fileHeader.Header.Get("Content-Type"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -111,40 +193,203 @@ func (h *ServerHandler) ImportServersFromExcel(c *gin.Context) {
 	}
 	defer file.Close()
 
-	response, err := h.service.ImportServersFromExcel(c.Request.Context(), file)
+	if async {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			h.logger.Error("Failed to read file", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+
+		job, err := h.imports.SubmitImport(c.Request.Context(), fileHeader.Filename, format, opts, data)
+		if err != nil {
+			h.logger.Error("Failed to submit import job", "format", format, "error", err)
+			if errors.Is(err, server.ErrImportQueueFull) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import servers"})
+			return
+		}
+
+		h.logger.Info("Import job submitted", "job_id", job.ID, "filename", fileHeader.Filename, "format", format)
+		c.Header("Location", "/api/v1/imports/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	response, err := h.service.ImportServers(c.Request.Context(), file, format, opts)
 	if err != nil {
-		h.logger.Error("Failed to import servers from Excel", "error", err)
+		h.logger.Error("Failed to import servers", "format", format, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import servers"})
 		return
 	}
 
 	h.logger.Info("Servers imported successfully",
 		"filename", fileHeader.Filename,
-		"success_count", response.SuccessCount,
+		"format", format,
+		"mode", opts.Mode,
+		"dry_run", opts.DryRun,
+		"atomic", opts.Atomic,
+		"created_count", response.CreatedCount,
+		"updated_count", response.UpdatedCount,
+		"unchanged_count", response.UnchangedCount,
+		"deleted_count", response.DeletedCount,
+		"skipped_count", response.SkippedCount,
 		"failure_count", response.FailureCount)
+
+	if opts.Report && response.Report != nil {
+		filename := fmt.Sprintf("import_report_%s.xlsx", time.Now().Format("20060102_150405"))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Header("X-Import-Success-Count", strconv.Itoa(response.SuccessCount))
+		c.Header("X-Import-Failure-Count", strconv.Itoa(response.FailureCount))
+		c.Data(http.StatusOK, server.FormatXLSX.ContentType(), response.Report)
+		return
+	}
 	c.JSON(http.StatusOK, response)
 }
 
-// Helper functions
-func isValidExcelFile(filename string) bool {
-	ext := filepath.Ext(filename)
-	return ext == ".xlsx" || ext == ".xls"
+func (h *ServerHandler) ImportTemplate(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := h.service.ImportTemplate(c.Request.Context(), &buf); err != nil {
+		h.logger.Error("Failed to build import template", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build import template"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="servers_import_template.xlsx"`)
+	c.Data(http.StatusOK, server.FormatXLSX.ContentType(), buf.Bytes())
 }
 
-func (h *ServerHandler) ExportServersToExcel(c *gin.Context) {
-	var req server.QueryServerRequest
+func (h *ServerHandler) ExportServers(c *gin.Context) {
+	writeExport(c, h.logger, "servers", h.service.ExportServers)
+}
+
+// writeExport binds an export request from the query string, negotiates its format and
+// locale, and streams the result of export as an attachment named after prefix
+func writeExport(c *gin.Context, log logger.Logger, prefix string, export func(ctx context.Context, req server.ExportServerRequest, w io.Writer) error) {
+	var req server.ExportServerRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		h.logger.Error("Failed to bind query", "error", err)
+		log.Error("Failed to bind query", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
-	if err := h.service.ExportServersToExcel(c.Request.Context(), req); err != nil {
-		h.logger.Error("Failed to export servers to Excel", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export servers"})
+	format, err := negotiateExportFormat(string(req.Format), c.GetHeader("Accept"))
+	if err != nil {
+		log.Error("Unsupported export format", "format", req.Format, "accept", c.GetHeader("Accept"))
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
 		return
 	}
+	req.Format = format
+	if req.Locale == "" {
+		req.Locale = preferredLanguage(c.GetHeader("Accept-Language"))
+	}
 
-	h.logger.Info("Servers exported successfully")
-	c.Status(http.StatusNoContent)
+	// XLSX is only written once every row has been collected, so a failure
+	// while querying can still be reported as a JSON error
+	filename := fmt.Sprintf("%s_export_%s.%s", prefix, time.Now().Format("20060102_150405"), format.Extension())
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if err := export(c.Request.Context(), req, c.Writer); err != nil {
+		log.Error("Failed to export servers", "format", format, "error", err)
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "")
+			c.Header("Content-Disposition", "")
+			if errors.Is(err, server.ErrInvalidExportRequest) || errors.Is(err, server.ErrInvalidFilter) ||
+				errors.Is(err, server.ErrInvalidSort) {
+				c.JSON(http.StatusBadRequest, filterError(err))
+				return
+			}
+			if errors.Is(err, server.ErrGroupNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export servers"})
+		}
+		return
+	}
+
+	log.Info("Servers exported successfully", "filename", filename)
+}
+
+// negotiateExportFormat picks the export format from the format query parameter,
+// falling back to the Accept header and then to XLSX
+func negotiateExportFormat(format string, accept string) (server.ExportFormat, error) {
+	if format != "" {
+		return server.ParseExportFormat(format)
+	}
+
+	type candidate struct {
+		format server.ExportFormat
+		q      float64
+	}
+	var best *candidate
+	wildcard := accept == ""
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		if mediaType == "*/*" || mediaType == "application/*" {
+			wildcard = true
+			continue
+		}
+		if f, ok := server.ExportFormatForMediaType(mediaType); ok && (best == nil || q > best.q) {
+			best = &candidate{format: f, q: q}
+		}
+	}
+
+	if best != nil {
+		return best.format, nil
+	}
+	if wildcard {
+		return server.FormatXLSX, nil
+	}
+	return "", fmt.Errorf("none of the accepted media types can be exported, supported formats: xlsx, csv, json, ndjson")
+}
+
+// preferredLanguage returns the export locale matching Accept-Language, or "" for the default.
+// Only languages with translated headers are considered.
+func preferredLanguage(acceptLanguage string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if strings.HasPrefix(strings.ToLower(tag), "vi") && q > bestQ {
+			best, bestQ = "vi", q
+		}
+		if strings.HasPrefix(strings.ToLower(tag), "en") && q > bestQ {
+			best, bestQ = "", q
+		}
+	}
+	return best
+}
+
+// filterError is the response body of an invalid filter, with the position of a query error
+func filterError(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+	var queryErr *server.QueryError
+	if errors.As(err, &queryErr) {
+		body["position"] = queryErr.Pos
+	}
+	return body
 }
@@ -7,11 +7,17 @@ import (
 
 type Route struct {
 	serverHandler *handler.ServerHandler
+	importHandler *handler.ImportJobHandler
+	faultHandler  *handler.FaultHandler
+	groupHandler  *handler.GroupHandler
 }
 
-func NewRoute(serverHandler *handler.ServerHandler) *Route {
+func NewRoute(serverHandler *handler.ServerHandler, importHandler *handler.ImportJobHandler, faultHandler *handler.FaultHandler, groupHandler *handler.GroupHandler) *Route {
 	return &Route{
 		serverHandler: serverHandler,
+		importHandler: importHandler,
+		faultHandler:  faultHandler,
+		groupHandler:  groupHandler,
 	}
 }
 
@@ -63,12 +69,50 @@ func (r *Route) SetupRoutes() *gin.Engine {
 			// CRUD operations
 			servers.POST("", r.serverHandler.CreateServer)
 			servers.GET("", r.serverHandler.ViewServer)
+			servers.GET("/search", r.serverHandler.SearchServers)
+			servers.GET("/stats", r.serverHandler.ServerStats)
 			servers.PUT("/:id", r.serverHandler.UpdateServer)
 			servers.DELETE("/:id", r.serverHandler.DeleteServer)
 
 			// Import/Export operations
-			servers.POST("/import", r.serverHandler.ImportServersFromExcel)
-			servers.GET("/export", r.serverHandler.ExportServersToExcel)
+			servers.POST("/import", r.serverHandler.ImportServers)
+			servers.GET("/import/template", r.serverHandler.ImportTemplate)
+			servers.GET("/export", r.serverHandler.ExportServers)
+		}
+
+		// Server groups and group-level operations
+		groups := v1.Group("/groups")
+		{
+			groups.POST("", r.groupHandler.CreateGroup)
+			groups.GET("", r.groupHandler.ListGroups)
+			groups.GET("/:id", r.groupHandler.GetGroup)
+			groups.PUT("/:id", r.groupHandler.UpdateGroup)
+			groups.DELETE("/:id", r.groupHandler.DeleteGroup)
+
+			groups.POST("/:id/members", r.groupHandler.AddGroupMembers)
+			groups.DELETE("/:id/members", r.groupHandler.RemoveGroupMembers)
+
+			groups.POST("/:id/start", r.groupHandler.StartGroup)
+			groups.POST("/:id/stop", r.groupHandler.StopGroup)
+			groups.GET("/:id/export", r.groupHandler.ExportGroup)
+		}
+
+		// Background import jobs
+		imports := v1.Group("/imports")
+		{
+			imports.GET("/:id", r.importHandler.GetImportJob)
+			imports.GET("/:id/report", r.importHandler.GetImportJobReport)
+			imports.POST("/:id/cancel", r.importHandler.CancelImportJob)
+		}
+
+		// Admin routes, fault injection for chaos testing of simulated servers
+		// is only exposed when enabled in the configuration
+		if r.faultHandler != nil {
+			admin := v1.Group("/admin")
+			admin.POST("/faults", r.faultHandler.InjectFault)
+			admin.GET("/faults", r.faultHandler.ListFaults)
+			admin.DELETE("/faults", r.faultHandler.ClearFaults)
+			admin.DELETE("/faults/:id", r.faultHandler.ClearFault)
 		}
 	}
 
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	_ "time/tzdata" // export time zones, the runtime image has no zoneinfo
 
 	"github.com/lits-06/vcs-sms/api/handler"
 	"github.com/lits-06/vcs-sms/api/router"
 	"github.com/lits-06/vcs-sms/config"
 	"github.com/lits-06/vcs-sms/infrastructure/database"
 	infraServer "github.com/lits-06/vcs-sms/infrastructure/server"
+	"github.com/lits-06/vcs-sms/pkg/lifecycle"
 	"github.com/lits-06/vcs-sms/pkg/logger"
 	"github.com/lits-06/vcs-sms/usecases/server"
 )
@@ -31,24 +36,94 @@ func main() {
 		appLogger.Fatal("Failed to connect to database", "error", err)
 	}
 
-	if err := database.AutoMigrate(db); err != nil {
+	migration, err := database.AutoMigrate(db)
+	if err != nil {
 		appLogger.Fatal("Failed to run migrations", "error", err)
 	}
+	if len(migration.InvalidIPv4) > 0 {
+		appLogger.Warn("Cleared IPv4 values of servers that were not IPv4 addresses",
+			"count", len(migration.InvalidIPv4), "server_ids", migration.InvalidIPv4)
+	}
+	if len(migration.MissingInterfaces) > 0 {
+		appLogger.Warn("Servers left without network interfaces because their address is used by another server",
+			"count", len(migration.MissingInterfaces), "server_ids", migration.MissingInterfaces)
+	}
 	appLogger.Info("Database connected successfully")
 
 	serverRepo := database.NewServerRepository(db)
-	serverProvider := infraServer.NewPortServerProvider()
-	serverUsecase := server.NewServerUsecase(serverRepo, serverProvider)
-	serverHandler := handler.NewServerHandler(serverUsecase, appLogger)
+	portAllocator, err := infraServer.NewPortAllocator(
+		cfg.Provider.Ports.Min,
+		cfg.Provider.Ports.Max,
+		[]int{cfg.Server.Port},
+		database.NewPortRepository(db),
+	)
+	if err != nil {
+		appLogger.Fatal("Failed to create port allocator", "error", err)
+	}
+
+	faultInjector := infraServer.NewFaultInjector()
+
+	providerRegistry := server.NewProviderRegistry(cfg.Provider.Default)
+	providerRegistry.Register(server.ProviderPort, infraServer.NewPortServerProvider(portAllocator, faultInjector, serverRepo))
+	providerRegistry.Register(server.ProviderProcess, infraServer.NewProcessServerProvider(
+		cfg.Provider.Process.Command, portAllocator, database.NewProcessRepository(db)))
+	providerRegistry.RegisterUnmanaged(server.ProviderExternal, infraServer.NewExternalServerProvider(
+		cfg.Provider.External.HealthCheckPort,
+		cfg.Provider.External.HealthCheckTimeout,
+		serverRepo,
+	))
+	serverUsecase := server.NewServerUsecase(serverRepo, providerRegistry)
+	importQueue := server.NewImportJobQueue(serverUsecase, database.NewImportJobRepository(db), cfg.Import.Workers, cfg.Import.QueueSize)
+	serverHandler := handler.NewServerHandler(serverUsecase, importQueue, appLogger)
+	importHandler := handler.NewImportJobHandler(importQueue, appLogger)
+	var faultHandler *handler.FaultHandler // admin routes are only registered when fault injection is enabled
+	if cfg.Provider.FaultInjection {
+		faultHandler = handler.NewFaultHandler(faultInjector, appLogger)
+	}
+	groupHandler := handler.NewGroupHandler(server.NewGroupUsecase(database.NewGroupRepository(db), serverUsecase), appLogger)
 
-	routes := router.NewRoute(serverHandler)
+	routes := router.NewRoute(serverHandler, importHandler, faultHandler, groupHandler)
 	r := routes.SetupRoutes()
 
+	httpServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	// Shutdown order: stop accepting requests and drain in-flight ones,
+	// stop background workers, release provider-managed servers, close the database
+	app := lifecycle.New(appLogger, cfg.Server.ShutdownTimeout)
+	app.Go("import jobs", importQueue.Run)
+	app.OnShutdown("http server", httpServer.Shutdown)
+	app.OnShutdown("background workers", app.StopWorkers)
+	app.OnShutdown("server providers", func(ctx context.Context) error {
+		return providerRegistry.Shutdown(ctx, cfg.Provider.ShutdownPolicy)
+	})
+	app.OnShutdown("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+
 	/// Start server
-	addr := fmt.Sprintf(":%d", cfg.Server.Port)
-	appLogger.Info("Server starting", "address", addr)
+	appLogger.Info("Server starting", "address", httpServer.Addr)
+
+	runErr := app.Run(httpServer.ListenAndServe)
+	if runErr != nil {
+		appLogger.Error("Server stopped with errors", "error", runErr)
+	} else {
+		appLogger.Info("Server stopped gracefully")
+	}
+
+	// Flush buffered log entries before exiting
+	_ = appLogger.Close()
 
-	if err := r.Run(addr); err != nil {
-		appLogger.Fatal("Failed to start server", "error", err)
+	if runErr != nil {
+		os.Exit(1)
 	}
 }
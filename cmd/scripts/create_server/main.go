@@ -8,20 +8,25 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"strings"
 	"sync"
 )
 
 type CreateServerRequest struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	IPv4   string `json:"ipv4"`
-	Status string `json:"status"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	IPv4        string            `json:"ipv4"`
+	IPv6        string            `json:"ipv6,omitempty"`
+	Status      string            `json:"status"`
+	Environment string            `json:"environment"`
+	Location    string            `json:"location"`
+	Labels      map[string]string `json:"labels"`
 }
 
 var (
 	serverTypes  = []string{"Web", "Database", "Cache", "Load Balancer", "API", "File", "Mail", "DNS", "Backup", "Monitor"}
-	environments = []string{"Production", "Staging", "Development", "Testing", "Demo"}
-	locations    = []string{"US-East", "US-West", "EU-Central", "Asia-Pacific", "Canada", "Australia"}
+	environments = []string{"production", "staging", "development", "testing", "demo"}
+	locations    = []string{"us-east", "us-west", "eu-central", "asia-pacific", "canada", "australia"}
 	statuses     = []string{"ON", "OFF"}
 )
 
@@ -50,11 +55,19 @@ func main() {
 }
 
 func createServer(baseURL string, id int) {
+	serverType := serverTypes[rand.Intn(len(serverTypes))]
 	req := CreateServerRequest{
-		ID:     generateRandomID(),
-		Name:   generateRandomName(),
-		IPv4:   generateRandomIPv4(),
-		Status: statuses[rand.Intn(len(statuses))],
+		ID:          generateRandomID(),
+		Name:        generateRandomName(serverType),
+		IPv4:        generateRandomIPv4(),
+		Status:      statuses[rand.Intn(len(statuses))],
+		Environment: environments[rand.Intn(len(environments))],
+		Location:    locations[rand.Intn(len(locations))],
+		Labels:      map[string]string{"type": strings.ToLower(strings.ReplaceAll(serverType, " ", "-"))},
+	}
+	// Một nửa số server có thêm địa chỉ IPv6
+	if rand.Intn(2) == 0 {
+		req.IPv6 = generateRandomIPv6()
 	}
 
 	jsonData, _ := json.Marshal(req)
@@ -83,13 +96,10 @@ func generateRandomID() string {
 }
 
 // Tạo tên server ngẫu nhiên
-func generateRandomName() string {
-	serverType := serverTypes[rand.Intn(len(serverTypes))]
-	environment := environments[rand.Intn(len(environments))]
-	location := locations[rand.Intn(len(locations))]
-	number := rand.Intn(99) + 1
+func generateRandomName(serverType string) string {
+	number := rand.Intn(9999) + 1
 
-	return fmt.Sprintf("%s Server %02d (%s - %s)", serverType, number, environment, location)
+	return fmt.Sprintf("%s Server %04d", serverType, number)
 }
 
 // Tạo IPv4 ngẫu nhiên (trong dải private networks)
@@ -114,3 +124,8 @@ func generateRandomIPv4() string {
 		return fmt.Sprintf("192.168.%d.%d", rand.Intn(256), rand.Intn(254)+1)
 	}
 }
+
+// Tạo IPv6 ngẫu nhiên (trong dải unique local fd00::/8)
+func generateRandomIPv6() string {
+	return fmt.Sprintf("fd00:%x:%x::%x", rand.Intn(0x10000), rand.Intn(0x10000), rand.Intn(0xfffe)+1)
+}
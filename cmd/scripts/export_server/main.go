@@ -6,8 +6,11 @@ import (
 	"io"
 	"log"
 	"math/rand"
+	"mime"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -29,7 +32,7 @@ type ExportResponse struct {
 var (
 	filterNames    = []string{"", "Web", "Database", "API", "Cache", "Load Balancer", "File", "Mail"}
 	filterStatuses = []string{"", "ON", "OFF"}
-	filterIPs      = []string{"", "192.168", "10.", "172.16"}
+	filterIPs      = []string{"", "192.168", "10.", "172.16", "10.0.0.0/8", "192.168.0.0/16", "172.16.0.1-172.16.255.254"}
 	sortFields     = []string{"", "name", "status", "created_at", "updated_at"}
 	sortOrders     = []string{"", "asc", "desc"}
 )
@@ -122,8 +125,12 @@ func exportAndGetServers(params url.Values) *[]Server {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 204 {
-		fmt.Println("✅ Excel file exported successfully!")
+	if resp.StatusCode == 200 {
+		path, err := saveExport(resp)
+		if err != nil {
+			log.Fatalf("Error saving export file: %v", err)
+		}
+		fmt.Printf("✅ Excel file exported successfully to %s\n", path)
 	} else {
 		body, _ := io.ReadAll(resp.Body)
 		fmt.Printf("❌ Export failed (Status: %d): %s\n", resp.StatusCode, string(body))
@@ -166,6 +173,31 @@ func exportAndGetServers(params url.Values) *[]Server {
 	return response.Servers
 }
 
+// saveExport writes the downloaded workbook to the exports/ directory using
+// the filename suggested by the API
+func saveExport(resp *http.Response) (string, error) {
+	filename := fmt.Sprintf("servers_export_%s.xlsx", time.Now().Format("20060102_150405"))
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = filepath.Base(params["filename"])
+	}
+
+	if err := os.MkdirAll("exports", 0o755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join("exports", filename)
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
 func printExportResults(servers *[]Server) {
 	fmt.Println("📊 Export Results:")
 	fmt.Println("═══════════════════════════════════════════════════════════════════════════════════════")
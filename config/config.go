@@ -18,14 +18,17 @@ type Config struct {
 	SMTP          SMTPConfig          `mapstructure:"smtp"`
 	Logging       LoggingConfig       `mapstructure:"log"`
 	Monitoring    MonitoringConfig    `mapstructure:"monitoring"`
+	Provider      ProviderConfig      `mapstructure:"provider"`
+	Import        ImportConfig        `mapstructure:"import"`
 	App           AppConfig           `mapstructure:"app"`
 }
 
 type ServerConfig struct {
-	Port         int           `mapstructure:"port" validate:"required,min=1,max=65535"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Port            int           `mapstructure:"port" validate:"required,min=1,max=65535"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 }
 
 type DatabaseConfig struct {
@@ -84,6 +87,36 @@ type MonitoringConfig struct {
 	Interval time.Duration `mapstructure:"interval" validate:"required"`
 }
 
+type ProviderConfig struct {
+	Default        string                 `mapstructure:"default" validate:"omitempty,oneof=port process external"`
+	Ports          PortRangeConfig        `mapstructure:"ports"`
+	ShutdownPolicy string                 `mapstructure:"shutdown_policy" validate:"omitempty,oneof=stop detach"` // stop or detach managed servers on exit
+	Process        ProcessProviderConfig  `mapstructure:"process"`
+	External       ExternalProviderConfig `mapstructure:"external"`
+	FaultInjection bool                   `mapstructure:"fault_injection"` // exposes the /admin/faults routes for chaos testing
+}
+
+// PortRangeConfig is the range of local ports handed out to provider-managed servers
+type PortRangeConfig struct {
+	Min int `mapstructure:"min" validate:"min=1024,max=65535"`
+	Max int `mapstructure:"max" validate:"min=1024,max=65535,gtefield=Min"`
+}
+
+type ProcessProviderConfig struct {
+	Command []string `mapstructure:"command"` // "{port}" and "{id}" are substituted in arguments
+}
+
+type ExternalProviderConfig struct {
+	HealthCheckPort    int           `mapstructure:"health_check_port" validate:"omitempty,min=1,max=65535"`
+	HealthCheckTimeout time.Duration `mapstructure:"health_check_timeout"`
+}
+
+// ImportConfig controls background import jobs
+type ImportConfig struct {
+	Workers   int `mapstructure:"workers" validate:"min=1"`    // jobs processed at the same time
+	QueueSize int `mapstructure:"queue_size" validate:"min=1"` // jobs waiting before new imports are rejected
+}
+
 type AppConfig struct {
 	Environment string `mapstructure:"environment" validate:"required,oneof=development staging production"`
 	Name        string `mapstructure:"name" validate:"required"`
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ImportJob is a server import processed in the background
+type ImportJob struct {
+	ID            string          `json:"id" db:"id" gorm:"primaryKey;column:id"`
+	Status        ImportJobStatus `json:"status" db:"status" gorm:"column:status;index"`
+	Filename      string          `json:"filename" db:"filename" gorm:"column:filename"`
+	Format        string          `json:"format" db:"format" gorm:"column:format"`
+	Mode          string          `json:"mode" db:"mode" gorm:"column:mode"`
+	DryRun        bool            `json:"dry_run" db:"dry_run" gorm:"column:dry_run"`
+	Atomic        bool            `json:"atomic" db:"atomic" gorm:"column:atomic"`
+	TotalRows     int             `json:"total_rows" db:"total_rows" gorm:"column:total_rows"`
+	ProcessedRows int             `json:"processed_rows" db:"processed_rows" gorm:"column:processed_rows"`
+	SucceededRows int             `json:"succeeded_rows" db:"succeeded_rows" gorm:"column:succeeded_rows"`
+	FailedRows    int             `json:"failed_rows" db:"failed_rows" gorm:"column:failed_rows"`
+	Result        json.RawMessage `json:"result,omitempty" db:"result" gorm:"column:result;type:jsonb"` // import report once the job is finished
+	Error         string          `json:"error,omitempty" db:"error" gorm:"column:error"`
+	Data          []byte          `json:"-" db:"data" gorm:"column:data;type:bytea"`     // uploaded file, dropped once processing starts
+	Report        []byte          `json:"-" db:"report" gorm:"column:report;type:bytea"` // annotated XLSX copy of the file
+	CreatedAt     time.Time       `json:"created_at" db:"created_at" gorm:"column:created_at"`
+	StartedAt     *time.Time      `json:"started_at,omitempty" db:"started_at" gorm:"column:started_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty" db:"finished_at" gorm:"column:finished_at"`
+}
+
+func (ImportJob) TableName() string {
+	return "import_jobs"
+}
+
+// ImportJobStatus represents import job status constants
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+	ImportJobCancelled ImportJobStatus = "cancelled"
+)
+
+// Finished reports whether the job has stopped for good
+func (j *ImportJob) Finished() bool {
+	switch j.Status {
+	case ImportJobCompleted, ImportJobFailed, ImportJobCancelled:
+		return true
+	}
+	return false
+}
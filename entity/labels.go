@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Labels are free-form key/value pairs attached to a server, such as region=us-east
+type Labels map[string]string
+
+// MaxLabels is the number of labels a server may carry
+const MaxLabels = 64
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,61}[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9._-]{0,61}[A-Za-z0-9])?)?$`)
+)
+
+// ValidLabelKey reports whether key can be used as a label key: up to 63 letters, digits,
+// dots, dashes, underscores and slashes, starting and ending with a letter or digit
+func ValidLabelKey(key string) bool {
+	return labelKeyPattern.MatchString(key)
+}
+
+// ValidLabelValue reports whether value can be used as a label value: empty, or up to 63 letters,
+// digits, dots, dashes and underscores, starting and ending with a letter or digit
+func ValidLabelValue(value string) bool {
+	return labelValuePattern.MatchString(value)
+}
+
+// Validate checks label keys and values
+func (l Labels) Validate() error {
+	if len(l) > MaxLabels {
+		return fmt.Errorf("too many labels: %d (max %d)", len(l), MaxLabels)
+	}
+	for _, key := range l.Keys() {
+		if !ValidLabelKey(key) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if !ValidLabelValue(l[key]) {
+			return fmt.Errorf("invalid value %q for label %s", l[key], key)
+		}
+	}
+	return nil
+}
+
+// Keys returns the label keys in sorted order
+func (l Labels) Keys() []string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String formats the labels as key=value pairs separated by commas, sorted by key
+func (l Labels) String() string {
+	pairs := make([]string, 0, len(l))
+	for _, key := range l.Keys() {
+		pairs = append(pairs, key+"="+l[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Equal reports whether both label sets hold the same pairs
+func (l Labels) Equal(other Labels) bool {
+	if len(l) != len(other) {
+		return false
+	}
+	for key, value := range l {
+		if otherValue, ok := other[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabels parses key=value pairs separated by commas, as written by Labels.String
+func ParseLabels(s string) (Labels, error) {
+	labels := make(Labels)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q (use key=value)", pair)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if _, exists := labels[key]; exists {
+			return nil, fmt.Errorf("duplicate label %s", key)
+		}
+		labels[key] = value
+	}
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
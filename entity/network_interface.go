@@ -0,0 +1,177 @@
+package entity
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// NetworkInterface is a network interface of a server. Its addresses are unique across all servers.
+type NetworkInterface struct {
+	ID       uint   `json:"-" db:"id" gorm:"primaryKey;column:id"`
+	ServerID string `json:"-" db:"server_id" gorm:"column:server_id;uniqueIndex:idx_network_interfaces_server_name"`
+	Name     string `json:"name" db:"name" gorm:"column:name;uniqueIndex:idx_network_interfaces_server_name"` // such as eth0
+	IPv4     string `json:"ipv4,omitempty" db:"ipv4" gorm:"column:ipv4;type:inet;uniqueIndex;default:null"`
+	IPv6     string `json:"ipv6,omitempty" db:"ipv6" gorm:"column:ipv6;type:inet;uniqueIndex;default:null"`
+	MAC      string `json:"mac,omitempty" db:"mac" gorm:"column:mac;type:macaddr;uniqueIndex;default:null"`
+	Primary  bool   `json:"primary" db:"is_primary" gorm:"column:is_primary"` // the interface health checks probe
+}
+
+func (NetworkInterface) TableName() string {
+	return "network_interfaces"
+}
+
+// NetworkInterfaces are the network interfaces of a server
+type NetworkInterfaces []NetworkInterface
+
+// MaxNetworkInterfaces is the number of interfaces a server may have
+const MaxNetworkInterfaces = 16
+
+// DefaultInterfaceName names the interface created for servers given a single address
+const DefaultInterfaceName = "eth0"
+
+// Normalize validates the interfaces and rewrites their addresses in canonical form.
+// Names must be unique and every interface needs an address. When no interface is
+// marked primary the first one becomes primary; more than one primary is an error.
+func (n NetworkInterfaces) Normalize() error {
+	if len(n) > MaxNetworkInterfaces {
+		return fmt.Errorf("too many network interfaces: %d (max %d)", len(n), MaxNetworkInterfaces)
+	}
+
+	names := make(map[string]bool, len(n))
+	addresses := make(map[string]string, len(n)) // address -> interface name
+	primaries := 0
+	for i := range n {
+		iface := &n[i]
+		iface.Name = strings.TrimSpace(iface.Name)
+		if iface.Name == "" {
+			return fmt.Errorf("network interface %d has no name", i+1)
+		}
+		if names[iface.Name] {
+			return fmt.Errorf("duplicate network interface %s", iface.Name)
+		}
+		names[iface.Name] = true
+
+		if iface.IPv4 == "" && iface.IPv6 == "" && iface.MAC == "" {
+			return fmt.Errorf("network interface %s has no address", iface.Name)
+		}
+		if iface.IPv4 != "" {
+			addr, err := netip.ParseAddr(strings.TrimSpace(iface.IPv4))
+			if err != nil || !addr.Is4() {
+				return fmt.Errorf("invalid IPv4 address %q on interface %s", iface.IPv4, iface.Name)
+			}
+			iface.IPv4 = addr.String()
+		}
+		if iface.IPv6 != "" {
+			addr, err := netip.ParseAddr(strings.TrimSpace(iface.IPv6))
+			if err != nil || !addr.Is6() || addr.Is4In6() || addr.Zone() != "" {
+				return fmt.Errorf("invalid IPv6 address %q on interface %s", iface.IPv6, iface.Name)
+			}
+			iface.IPv6 = addr.String()
+		}
+		if iface.MAC != "" {
+			mac, err := net.ParseMAC(strings.TrimSpace(iface.MAC))
+			if err != nil || len(mac) != 6 {
+				return fmt.Errorf("invalid MAC address %q on interface %s", iface.MAC, iface.Name)
+			}
+			iface.MAC = mac.String()
+		}
+
+		for _, address := range []string{iface.IPv4, iface.IPv6, iface.MAC} {
+			if address == "" {
+				continue
+			}
+			if other, ok := addresses[address]; ok {
+				return fmt.Errorf("address %s is used by interfaces %s and %s", address, other, iface.Name)
+			}
+			addresses[address] = iface.Name
+		}
+
+		if iface.Primary {
+			primaries++
+		}
+	}
+
+	switch {
+	case primaries > 1:
+		return fmt.Errorf("only one network interface can be primary")
+	case primaries == 0 && len(n) > 0:
+		n[0].Primary = true
+	}
+	return nil
+}
+
+// Primary returns the primary interface, or false when there is none
+func (n NetworkInterfaces) Primary() (NetworkInterface, bool) {
+	for _, iface := range n {
+		if iface.Primary {
+			return iface, true
+		}
+	}
+	return NetworkInterface{}, false
+}
+
+// String formats the interfaces as name=address|address pairs separated by semicolons,
+// primary first, such as eth0=10.0.0.5|fd00::5|aa:bb:cc:dd:ee:ff; mgmt=10.1.0.5
+func (n NetworkInterfaces) String() string {
+	parts := make([]string, 0, len(n))
+	for _, iface := range n {
+		var addresses []string
+		for _, address := range []string{iface.IPv4, iface.IPv6, iface.MAC} {
+			if address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+		part := iface.Name + "=" + strings.Join(addresses, "|")
+		if iface.Primary {
+			parts = append([]string{part}, parts...)
+		} else {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ParseNetworkInterfaces parses interfaces as written by NetworkInterfaces.String.
+// The first interface is primary, and each address is recognised as IPv4, IPv6 or MAC by its form.
+func ParseNetworkInterfaces(s string) (NetworkInterfaces, error) {
+	var interfaces NetworkInterfaces
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, addresses, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid network interface %q (use name=address|address)", part)
+		}
+		iface := NetworkInterface{Name: strings.TrimSpace(name), Primary: len(interfaces) == 0}
+		for _, address := range strings.Split(addresses, "|") {
+			address = strings.TrimSpace(address)
+			if address == "" {
+				continue
+			}
+			var field *string
+			if addr, err := netip.ParseAddr(address); err == nil {
+				field = &iface.IPv6
+				if addr.Is4() {
+					field = &iface.IPv4
+				}
+			} else if _, err := net.ParseMAC(address); err == nil {
+				field = &iface.MAC
+			} else {
+				return nil, fmt.Errorf("invalid address %q on interface %s", address, iface.Name)
+			}
+			if *field != "" {
+				return nil, fmt.Errorf("interface %s has more than one address of the same kind", iface.Name)
+			}
+			*field = address
+		}
+		interfaces = append(interfaces, iface)
+	}
+	if err := interfaces.Normalize(); err != nil {
+		return nil, err
+	}
+	return interfaces, nil
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// PortAssignment records the local port allocated to a provider-managed server
+type PortAssignment struct {
+	ServerID  string     `json:"server_id" db:"server_id" gorm:"primaryKey;column:server_id"`
+	Port      int        `json:"port" db:"port" gorm:"column:port;uniqueIndex"`
+	PID       int        `json:"pid,omitempty" db:"pid" gorm:"column:pid;default:0"`            // process running the server, 0 when stopped
+	StartedAt *time.Time `json:"started_at,omitempty" db:"started_at" gorm:"column:started_at"` // when the process was started
+	CreatedAt time.Time  `json:"created_at" db:"created_at" gorm:"column:created_at"`
+}
+
+func (PortAssignment) TableName() string {
+	return "port_assignments"
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Latency distributions supported by simulated servers
+const (
+	LatencyFixed       = "fixed"       // always MeanMs
+	LatencyUniform     = "uniform"     // between MinMs and MaxMs
+	LatencyNormal      = "normal"      // MeanMs +/- StdDevMs, clamped to [MinMs, MaxMs] when set
+	LatencyExponential = "exponential" // exponential with mean MeanMs, capped at MaxMs when set
+)
+
+// SimulationProfile describes how a simulated server answers requests
+type SimulationProfile struct {
+	Latency      LatencyProfile      `json:"latency"`
+	ErrorRate    float64             `json:"error_rate" validate:"min=0,max=1"`          // share of requests answered with 500
+	ResponseSize int                 `json:"response_size" validate:"min=0,max=1048576"` // bytes returned by "/", 0 keeps the default message
+	Endpoints    []SimulatedEndpoint `json:"endpoints,omitempty"`
+}
+
+type LatencyProfile struct {
+	Distribution string `json:"distribution,omitempty" validate:"omitempty,oneof=fixed uniform normal exponential"`
+	MeanMs       int    `json:"mean_ms,omitempty" validate:"min=0"`
+	StdDevMs     int    `json:"stddev_ms,omitempty" validate:"min=0"`
+	MinMs        int    `json:"min_ms,omitempty" validate:"min=0"`
+	MaxMs        int    `json:"max_ms,omitempty" validate:"min=0"`
+}
+
+// SimulatedEndpoint is an extra path served by a simulated server
+type SimulatedEndpoint struct {
+	Path        string `json:"path" validate:"required,startswith=/"`
+	StatusCode  int    `json:"status_code,omitempty" validate:"omitempty,min=100,max=599"`
+	Body        string `json:"body,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Validate checks the profile for values simulated servers cannot honour
+func (p *SimulationProfile) Validate() error {
+	if p.ErrorRate < 0 || p.ErrorRate > 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1")
+	}
+	if p.ResponseSize < 0 || p.ResponseSize > 1<<20 {
+		return fmt.Errorf("response_size must be between 0 and 1048576")
+	}
+
+	latency := p.Latency
+	switch latency.Distribution {
+	case "", LatencyFixed, LatencyNormal, LatencyExponential:
+	case LatencyUniform:
+		if latency.MaxMs < latency.MinMs {
+			return fmt.Errorf("latency max_ms must not be lower than min_ms")
+		}
+	default:
+		return fmt.Errorf("unknown latency distribution %q", latency.Distribution)
+	}
+	if latency.MeanMs < 0 || latency.StdDevMs < 0 || latency.MinMs < 0 || latency.MaxMs < 0 {
+		return fmt.Errorf("latency values must not be negative")
+	}
+
+	seen := make(map[string]bool, len(p.Endpoints))
+	for _, endpoint := range p.Endpoints {
+		if !strings.HasPrefix(endpoint.Path, "/") {
+			return fmt.Errorf("endpoint path %q must start with /", endpoint.Path)
+		}
+		if endpoint.Path == "/" || endpoint.Path == "/status" {
+			return fmt.Errorf("endpoint path %q is reserved", endpoint.Path)
+		}
+		if seen[endpoint.Path] {
+			return fmt.Errorf("duplicate endpoint path %q", endpoint.Path)
+		}
+		if endpoint.StatusCode != 0 && (endpoint.StatusCode < 100 || endpoint.StatusCode > 599) {
+			return fmt.Errorf("endpoint %q has invalid status code %d", endpoint.Path, endpoint.StatusCode)
+		}
+		seen[endpoint.Path] = true
+	}
+
+	return nil
+}
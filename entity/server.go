@@ -6,20 +6,49 @@ import (
 
 // Server represents a server entity
 type Server struct {
-	ID        string       `json:"id" db:"id" gorm:"primaryKey;column:id" validate:"required"`
-	Name      string       `json:"name" db:"name" gorm:"column:name;uniqueIndex" validate:"required"`
-	Host      string       `json:"host" gorm:"-" validate:"omitempty"`
-	Port      int          `json:"port" gorm:"-" validate:"omitempty,min=1024,max=65535"`
-	Status    ServerStatus `json:"status" db:"status" gorm:"column:status" validate:"omitempty,oneof=ON OFF"`
-	CreatedAt time.Time    `json:"created_at" db:"created_at" gorm:"column:created_at"`
-	UpdatedAt time.Time    `json:"updated_at" db:"updated_at" gorm:"column:updated_at,autoUpdateTime"`
-	IPv4      string       `json:"ipv4" db:"ipv4" gorm:"column:ipv4" validate:"omitempty,ipv4"`
+	ID        string             `json:"id" db:"id" gorm:"primaryKey;column:id" validate:"required"`
+	Name      string             `json:"name" db:"name" gorm:"column:name;uniqueIndex" validate:"required"`
+	Host      string             `json:"host,omitempty" gorm:"-" validate:"omitempty"`
+	Port      int                `json:"port,omitempty" gorm:"-" validate:"omitempty,min=1024,max=65535"`
+	Status    ServerStatus       `json:"status" db:"status" gorm:"column:status" validate:"omitempty,oneof=ON OFF"`
+	CreatedAt time.Time          `json:"created_at" db:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time          `json:"updated_at" db:"updated_at" gorm:"column:updated_at,autoUpdateTime"`
+	IPv4      string             `json:"ipv4" db:"ipv4" gorm:"column:ipv4;type:inet;index;default:null" validate:"omitempty,ipv4"` // IPv4 address of the primary interface
+	IPv6      string             `json:"ipv6,omitempty" db:"ipv6" gorm:"column:ipv6;type:inet;index;default:null"`                 // IPv6 address of the primary interface
+	Provider  string             `json:"provider" db:"provider" gorm:"column:provider;default:port;index"`
+	Profile   *SimulationProfile `json:"profile,omitempty" db:"profile" gorm:"column:profile;type:jsonb;serializer:json"`
+
+	// Metadata
+	Description string `json:"description,omitempty" db:"description" gorm:"column:description"`
+	Environment string `json:"environment,omitempty" db:"environment" gorm:"column:environment;index"` // such as production or staging
+	Location    string `json:"location,omitempty" db:"location" gorm:"column:location;index"`          // such as us-east or eu-central
+	Owner       string `json:"owner,omitempty" db:"owner" gorm:"column:owner;index"`
+	Labels      Labels `json:"labels,omitempty" db:"labels" gorm:"column:labels;type:jsonb;serializer:json;index:,type:gin"`
+
+	Interfaces NetworkInterfaces `json:"interfaces,omitempty" gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE"`
 }
 
 func (Server) TableName() string {
 	return "servers"
 }
 
+// PrimaryAddress returns the address health checks probe: the IPv4 address of the
+// primary interface, or its IPv6 address when it has none
+func (s Server) PrimaryAddress() string {
+	if s.IPv4 != "" {
+		return s.IPv4
+	}
+	return s.IPv6
+}
+
+// SetInterfaces replaces the server's interfaces and takes its addresses from the primary one
+func (s *Server) SetInterfaces(interfaces NetworkInterfaces) {
+	s.Interfaces = interfaces
+	primary, _ := interfaces.Primary()
+	s.IPv4 = primary.IPv4
+	s.IPv6 = primary.IPv6
+}
+
 // ServerStatus represents server status constants
 type ServerStatus string
 
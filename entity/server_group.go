@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// ServerGroup is a named set of servers managed together, such as a database cluster.
+// A server can belong to any number of groups.
+type ServerGroup struct {
+	ID          string    `json:"id" db:"id" gorm:"primaryKey;column:id"`
+	Name        string    `json:"name" db:"name" gorm:"column:name;uniqueIndex"`
+	Description string    `json:"description,omitempty" db:"description" gorm:"column:description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at" gorm:"column:updated_at"`
+
+	ServerIDs []string `json:"server_ids" gorm:"-"` // IDs of the member servers, sorted
+}
+
+func (ServerGroup) TableName() string {
+	return "server_groups"
+}
+
+// ServerGroupMember records that a server belongs to a group.
+// Memberships are removed with their group or server.
+type ServerGroupMember struct {
+	GroupID   string    `json:"group_id" db:"group_id" gorm:"primaryKey;column:group_id"`
+	ServerID  string    `json:"server_id" db:"server_id" gorm:"primaryKey;column:server_id;index"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" gorm:"column:created_at"`
+
+	Group  *ServerGroup `json:"-" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	Server *Server      `json:"-" gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE"`
+}
+
+func (ServerGroupMember) TableName() string {
+	return "server_group_members"
+}
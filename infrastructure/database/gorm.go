@@ -2,7 +2,10 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/netip"
+	"strings"
 	"time"
 
 	"github.com/lits-06/vcs-sms/config"
@@ -10,15 +13,116 @@ import (
 	"github.com/lits-06/vcs-sms/usecases/server"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
+// MigrationReport lists the data AutoMigrate could not carry over, which needs fixing by hand
+type MigrationReport struct {
+	// IDs of servers whose IPv4 was not an IPv4 address and has been cleared
+	InvalidIPv4 []string
+	// IDs of servers left without network interfaces because another server has their address
+	MissingInterfaces []string
+}
+
 // AutoMigrate runs database migrations
-func AutoMigrate(db *gorm.DB) error {
-	if err := db.AutoMigrate(&entity.Server{}); err != nil {
-		return fmt.Errorf("failed to auto migrate: %w", err)
+func AutoMigrate(db *gorm.DB) (*MigrationReport, error) {
+	report := &MigrationReport{}
+	invalid, err := migrateIPv4Column(db)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	report.InvalidIPv4 = invalid
+
+	if err := db.AutoMigrate(&entity.Server{}, &entity.NetworkInterface{}, &entity.PortAssignment{}, &entity.ImportJob{},
+		&entity.ServerGroup{}, &entity.ServerGroupMember{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate: %w", err)
+	}
+
+	// Servers created before network interfaces were introduced get a primary eth0 interface.
+	// Addresses must be unique across interfaces, so servers sharing one are reported instead.
+	err = db.Exec(`
+		INSERT INTO network_interfaces (server_id, name, ipv4, ipv6, is_primary)
+		SELECT s.id, ?, s.ipv4, s.ipv6, true FROM servers s
+		WHERE (s.ipv4 IS NOT NULL OR s.ipv6 IS NOT NULL)
+			AND NOT EXISTS (SELECT 1 FROM network_interfaces ni WHERE ni.server_id = s.id)
+		ON CONFLICT DO NOTHING`, entity.DefaultInterfaceName).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to add network interfaces of existing servers: %w", err)
+	}
+	err = db.Model(&entity.Server{}).
+		Where("(ipv4 IS NOT NULL OR ipv6 IS NOT NULL) AND NOT EXISTS (SELECT 1 FROM network_interfaces ni WHERE ni.server_id = servers.id)").
+		Order("id").Pluck("id", &report.MissingInterfaces).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check network interfaces of existing servers: %w", err)
+	}
+
+	for _, statement := range searchMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return nil, fmt.Errorf("failed to set up server search: %w", err)
+		}
+	}
+	for _, statement := range sortMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return nil, fmt.Errorf("failed to set up server sorting: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// migrateIPv4Column converts a text servers.ipv4 column, from before addresses were stored
+// as inet, so that AutoMigrate does not abort on values that are not addresses. Such values
+// are cleared and the IDs of their servers returned.
+func migrateIPv4Column(db *gorm.DB) ([]string, error) {
+	var dataType string
+	err := db.Raw(`SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'servers' AND column_name = 'ipv4'`).Scan(&dataType).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check servers.ipv4 column: %w", err)
+	}
+	if dataType == "" || dataType == "inet" {
+		return nil, nil
+	}
+
+	var invalid []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// A temporary function lives on the transaction's connection only
+		err := tx.Exec(`CREATE OR REPLACE FUNCTION pg_temp.ipv4_address(value text) RETURNS inet
+			LANGUAGE plpgsql IMMUTABLE AS $$
+			DECLARE address inet;
+			BEGIN
+				address := NULLIF(trim(value), '')::inet;
+				IF family(address) = 4 AND masklen(address) = 32 THEN
+					RETURN address;
+				END IF;
+				RETURN NULL;
+			EXCEPTION WHEN invalid_text_representation THEN
+				RETURN NULL;
+			END $$`).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Raw(`SELECT id FROM servers
+			WHERE NULLIF(trim(ipv4), '') IS NOT NULL AND pg_temp.ipv4_address(ipv4) IS NULL ORDER BY id`).Scan(&invalid).Error
+		if err != nil {
+			return err
+		}
+		if len(invalid) > 0 {
+			err := tx.Exec(`UPDATE servers SET ipv4 = NULL
+				WHERE NULLIF(trim(ipv4), '') IS NOT NULL AND pg_temp.ipv4_address(ipv4) IS NULL`).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec(`ALTER TABLE servers ALTER COLUMN ipv4 TYPE inet USING NULLIF(trim(ipv4), '')::inet`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DROP FUNCTION pg_temp.ipv4_address(text)`).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert servers.ipv4 to inet: %w", err)
+	}
+	return invalid, nil
 }
 
 type GormDB struct {
@@ -71,15 +175,53 @@ func NewServerRepository(db *gorm.DB) server.Repository {
 }
 
 func (r *gormServerRepository) Create(ctx context.Context, srv *entity.Server) error {
-	if err := r.db.WithContext(ctx).Create(srv).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Interfaces are inserted separately, GORM would ignore conflicting addresses
+		if err := tx.Omit(clause.Associations).Create(srv).Error; err != nil {
+			return err
+		}
+		return createInterfaces(tx, []entity.Server{*srv})
+	})
+	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
 	return nil
 }
 
+// createInterfaces inserts the network interfaces of servers
+func createInterfaces(tx *gorm.DB, servers []entity.Server) error {
+	var interfaces []entity.NetworkInterface
+	for _, srv := range servers {
+		for _, iface := range srv.Interfaces {
+			iface.ID = 0
+			iface.ServerID = srv.ID
+			interfaces = append(interfaces, iface)
+		}
+	}
+	if len(interfaces) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(interfaces, 1000).Error
+}
+
+// preloadInterfaces loads the network interfaces of the queried servers, primary first
+func preloadInterfaces(query *gorm.DB) *gorm.DB {
+	return query.Preload("Interfaces", func(db *gorm.DB) *gorm.DB {
+		return db.Order("is_primary DESC, name")
+	})
+}
+
+// nullable returns nil for an empty value, which is stored as NULL
+func nullable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
 func (r *gormServerRepository) GetByID(ctx context.Context, id string) (*entity.Server, error) {
 	var srv entity.Server
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&srv).Error
+	err := preloadInterfaces(r.db.WithContext(ctx)).Where("id = ?", id).First(&srv).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil // Not found
@@ -109,21 +251,55 @@ func (r *gormServerRepository) Update(ctx context.Context, srv *entity.Server) e
 	if srv.Status != "" {
 		data["status"] = srv.Status
 	}
-	if srv.IPv4 != "" {
-		data["ipv4"] = srv.IPv4
+	// Addresses follow the primary interface, which may lack either of them
+	data["ipv4"] = nullable(srv.IPv4)
+	data["ipv6"] = nullable(srv.IPv6)
+	if srv.Profile != nil {
+		profile, err := json.Marshal(srv.Profile)
+		if err != nil {
+			return fmt.Errorf("failed to encode server profile: %w", err)
+		}
+		data["profile"] = string(profile)
 	}
 
-	result := r.db.WithContext(ctx).Model(srv).Where("id = ?", srv.ID).Updates(data)
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to update server: %w", result.Error)
+	// Metadata is always written, so it can be cleared
+	data["description"] = srv.Description
+	data["environment"] = srv.Environment
+	data["location"] = srv.Location
+	data["owner"] = srv.Owner
+	labels := srv.Labels
+	if labels == nil {
+		labels = entity.Labels{}
 	}
-
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("server with ID %s not found", srv.ID)
+	encodedLabels, err := json.Marshal(labels)
+	if err != nil {
+		return fmt.Errorf("failed to encode server labels: %w", err)
 	}
+	data["labels"] = string(encodedLabels)
 
-	return nil
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(srv).Where("id = ?", srv.ID).Updates(data)
+
+		if result.Error != nil {
+			return fmt.Errorf("failed to update server: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("server with ID %s not found", srv.ID)
+		}
+
+		// Interfaces, when loaded, are replaced as a whole
+		if srv.Interfaces == nil {
+			return nil
+		}
+		if err := tx.Where("server_id = ?", srv.ID).Delete(&entity.NetworkInterface{}).Error; err != nil {
+			return fmt.Errorf("failed to update network interfaces: %w", err)
+		}
+		if err := createInterfaces(tx, []entity.Server{*srv}); err != nil {
+			return fmt.Errorf("failed to update network interfaces: %w", err)
+		}
+		return nil
+	})
 }
 
 func (r *gormServerRepository) Delete(ctx context.Context, id string) error {
@@ -140,7 +316,118 @@ func (r *gormServerRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (r *gormServerRepository) CreateBatch(ctx context.Context, servers []entity.Server, batchSize int) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).CreateInBatches(servers, batchSize).Error; err != nil {
+			return err
+		}
+		return createInterfaces(tx, servers)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create servers: %w", err)
+	}
+	return nil
+}
+
+// lookupChunkSize keeps IN lists well below PostgreSQL's limit of 65535 bind parameters
+const lookupChunkSize = 5000
+
+func (r *gormServerRepository) FindByIDsOrNames(ctx context.Context, ids []string, names []string) ([]entity.Server, error) {
+	var servers []entity.Server
+	seen := make(map[string]bool)
+
+	for start := 0; start < len(ids) || start < len(names); start += lookupChunkSize {
+		idChunk := chunk(ids, start, lookupChunkSize)
+		nameChunk := chunk(names, start, lookupChunkSize)
+
+		query := preloadInterfaces(r.db.WithContext(ctx).Model(&entity.Server{}))
+		switch {
+		case len(idChunk) > 0 && len(nameChunk) > 0:
+			query = query.Where("id IN ? OR name IN ?", idChunk, nameChunk)
+		case len(idChunk) > 0:
+			query = query.Where("id IN ?", idChunk)
+		default:
+			query = query.Where("name IN ?", nameChunk)
+		}
+
+		var found []entity.Server
+		if err := query.Find(&found).Error; err != nil {
+			return nil, fmt.Errorf("failed to find servers: %w", err)
+		}
+		for _, srv := range found {
+			if !seen[srv.ID] {
+				seen[srv.ID] = true
+				servers = append(servers, srv)
+			}
+		}
+	}
+
+	return servers, nil
+}
+
+func (r *gormServerRepository) FindInterfaces(ctx context.Context, ipv4s []string, ipv6s []string, macs []string) ([]entity.NetworkInterface, error) {
+	var interfaces []entity.NetworkInterface
+
+	for start := 0; start < len(ipv4s) || start < len(ipv6s) || start < len(macs); start += lookupChunkSize {
+		var conditions []string
+		var args []interface{}
+		for _, lookup := range []struct {
+			column string
+			values []string
+		}{
+			{"ipv4", chunk(ipv4s, start, lookupChunkSize)},
+			{"ipv6", chunk(ipv6s, start, lookupChunkSize)},
+			{"mac", chunk(macs, start, lookupChunkSize)},
+		} {
+			if len(lookup.values) > 0 {
+				conditions = append(conditions, lookup.column+" IN ?")
+				args = append(args, lookup.values)
+			}
+		}
+
+		var found []entity.NetworkInterface
+		err := r.db.WithContext(ctx).Where(strings.Join(conditions, " OR "), args...).Find(&found).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to find network interfaces: %w", err)
+		}
+		interfaces = append(interfaces, found...)
+	}
+
+	return interfaces, nil
+}
+
+func (r *gormServerRepository) Transaction(ctx context.Context, fn func(repo server.Repository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormServerRepository{db: tx})
+	})
+}
+
 func (r *gormServerRepository) List(ctx context.Context, filter server.ServerFilter, sort server.ServerSort, pagination server.ServerPagination) (*[]entity.Server, int, error) {
+	return r.ListFields(ctx, filter, sort, pagination, nil)
+}
+
+// fieldColumns maps the selectable server fields to their columns. Interfaces are preloaded instead.
+var fieldColumns = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"status":      "status",
+	"ipv4":        "ipv4",
+	"ipv6":        "ipv6",
+	"provider":    "provider",
+	"profile":     "profile",
+	"description": "description",
+	"environment": "environment",
+	"location":    "location",
+	"owner":       "owner",
+	"labels":      "labels",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
+func (r *gormServerRepository) ListFields(ctx context.Context, filter server.ServerFilter, sort server.ServerSort, pagination server.ServerPagination, fields []string) (*[]entity.Server, int, error) {
 	var servers []entity.Server
 	var total int64
 
@@ -159,6 +446,29 @@ func (r *gormServerRepository) List(ctx context.Context, filter server.ServerFil
 	// Apply pagination
 	query = r.applyPagination(query, pagination)
 
+	// Load only the requested columns, the ID is always needed to preload interfaces
+	preload := len(fields) == 0
+	if len(fields) > 0 {
+		columns := []string{"id"}
+		for _, field := range fields {
+			if field == "interfaces" {
+				preload = true
+				continue
+			}
+			column, ok := fieldColumns[field]
+			if !ok {
+				return nil, 0, fmt.Errorf("unknown server field %q", field)
+			}
+			if column != "id" {
+				columns = append(columns, column)
+			}
+		}
+		query = query.Select(columns)
+	}
+	if preload {
+		query = preloadInterfaces(query)
+	}
+
 	// Execute query
 	if err := query.Find(&servers).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to list servers: %w", err)
@@ -177,24 +487,198 @@ func (r *gormServerRepository) applyFilters(query *gorm.DB, filter server.Server
 	}
 
 	if filter.IPv4 != "" {
-		query = query.Where("ipv4 = ?", filter.IPv4)
+		ranges, err := server.ParseIPFilter(filter.IPv4)
+		if err != nil {
+			_ = query.AddError(err)
+			return query
+		}
+		query = applyIPRanges(query, ranges)
+	}
+
+	if filter.IPv6 != "" {
+		prefixes, err := server.ParseIPv6Filter(filter.IPv6)
+		if err != nil {
+			_ = query.AddError(err)
+			return query
+		}
+		query = applyIPv6Prefixes(query, prefixes)
+	}
+
+	if filter.MAC != "" {
+		macs, err := server.ParseMACFilter(filter.MAC)
+		if err != nil {
+			_ = query.AddError(err)
+			return query
+		}
+		if len(macs) > 0 {
+			query = query.Where(interfaceCondition("ni.mac IN ?"), macs)
+		}
+	}
+
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+
+	if filter.Environment != "" {
+		query = query.Where("environment = ?", filter.Environment)
+	}
+
+	if filter.Location != "" {
+		query = query.Where("location = ?", filter.Location)
+	}
+
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+
+	if filter.Labels != "" {
+		selector, err := server.ParseLabelSelector(filter.Labels)
+		if err != nil {
+			_ = query.AddError(err)
+			return query
+		}
+		query = applyLabelSelector(query, selector)
+	}
+
+	if filter.Group != "" {
+		query = query.Where(`EXISTS (SELECT 1 FROM server_group_members m JOIN server_groups g ON g.id = m.group_id
+			WHERE m.server_id = servers.id AND (g.id = ? OR g.name = ?))`, filter.Group, filter.Group)
+	}
+
+	now := time.Now()
+	created, err := filter.CreatedRange(now)
+	if err != nil {
+		_ = query.AddError(err)
+		return query
+	}
+	query = applyTimeRange(query, "created_at", created)
+	updated, err := filter.UpdatedRange(now)
+	if err != nil {
+		_ = query.AddError(err)
+		return query
+	}
+	query = applyTimeRange(query, "updated_at", updated)
+
+	if filter.Query != "" {
+		expr, err := server.ParseQuery(filter.Query)
+		if err != nil {
+			_ = query.AddError(err)
+			return query
+		}
+		if expr != nil {
+			condition, args := compileQuery(expr)
+			query = query.Where(condition, args...)
+		}
 	}
 
 	return query
 }
 
-func (r *gormServerRepository) applySorting(query *gorm.DB, sort server.ServerSort) *gorm.DB {
-	field := "created_at" // Default sort field
-	if sort.Sort != "" {
-		field = sort.Sort
+// applyTimeRange restricts column to the range r
+func applyTimeRange(query *gorm.DB, column string, r server.TimeRange) *gorm.DB {
+	if !r.From.IsZero() {
+		query = query.Where(column+" >= ?", r.From)
+	}
+	if !r.To.IsZero() {
+		query = query.Where(column+" < ?", r.To)
+	}
+	return query
+}
+
+// applyIPRanges matches servers with an interface whose IPv4 address is in any of ranges
+func applyIPRanges(query *gorm.DB, ranges []server.IPRange) *gorm.DB {
+	if len(ranges) == 0 {
+		return query
+	}
+	condition, args := ipRangesCondition(ranges)
+	return query.Where(condition, args...)
+}
+
+// ipRangesCondition matches servers with an interface whose IPv4 address is in any of ranges.
+// Both forms compare inet values, so they can use the index on network_interfaces.ipv4.
+func ipRangesCondition(ranges []server.IPRange) (string, []interface{}) {
+	conditions := make([]string, 0, len(ranges))
+	args := make([]interface{}, 0, 2*len(ranges))
+	for _, r := range ranges {
+		if r.Single() {
+			conditions = append(conditions, "ni.ipv4 = ?::inet")
+			args = append(args, r.From.String())
+		} else {
+			conditions = append(conditions, "ni.ipv4 BETWEEN ?::inet AND ?::inet")
+			args = append(args, r.From.String(), r.To.String())
+		}
+	}
+	return interfaceCondition(strings.Join(conditions, " OR ")), args
+}
+
+// applyIPv6Prefixes matches servers with an interface whose IPv6 address is in any of prefixes
+func applyIPv6Prefixes(query *gorm.DB, prefixes []netip.Prefix) *gorm.DB {
+	if len(prefixes) == 0 {
+		return query
 	}
+	condition, args := ipv6PrefixesCondition(prefixes)
+	return query.Where(condition, args...)
+}
 
-	order := "ASC" // Default order
-	if sort.Order == server.SortDesc {
-		order = "DESC"
+func ipv6PrefixesCondition(prefixes []netip.Prefix) (string, []interface{}) {
+	conditions := make([]string, 0, len(prefixes))
+	args := make([]interface{}, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		conditions = append(conditions, "ni.ipv6 <<= ?::inet")
+		args = append(args, prefix.String())
 	}
+	return interfaceCondition(strings.Join(conditions, " OR ")), args
+}
 
-	return query.Order(fmt.Sprintf("%s %s", field, order))
+// interfaceCondition matches servers with a network interface ni meeting condition
+func interfaceCondition(condition string) string {
+	return "EXISTS (SELECT 1 FROM network_interfaces ni WHERE ni.server_id = servers.id AND (" + condition + "))"
+}
+
+// applyLabelSelector adds a condition per requirement on the labels JSONB column.
+// Equality uses containment so the GIN index on labels can be used.
+func applyLabelSelector(query *gorm.DB, selector server.LabelSelector) *gorm.DB {
+	for _, requirement := range selector {
+		switch requirement.Operator {
+		case server.LabelEquals:
+			contained, _ := json.Marshal(map[string]string{requirement.Key: requirement.Values[0]})
+			query = query.Where("labels @> ?::jsonb", string(contained))
+		case server.LabelNotEquals:
+			query = query.Where("(labels ->> ?) IS DISTINCT FROM ?", requirement.Key, requirement.Values[0])
+		case server.LabelIn:
+			query = query.Where("labels ->> ? IN ?", requirement.Key, requirement.Values)
+		case server.LabelNotIn:
+			query = query.Where("(labels ->> ? IS NULL OR labels ->> ? NOT IN ?)", requirement.Key, requirement.Key, requirement.Values)
+		case server.LabelExists:
+			query = query.Where("labels ->> ? IS NOT NULL", requirement.Key)
+		case server.LabelDoesNotExist:
+			query = query.Where("labels ->> ? IS NULL", requirement.Key)
+		}
+	}
+	return query
+}
+
+// applySorting orders the query by the whitelisted fields of sort, servers missing a value last
+func (r *gormServerRepository) applySorting(query *gorm.DB, sort server.ServerSort) *gorm.DB {
+	keys, err := server.ParseSort(sort)
+	if err != nil {
+		_ = query.AddError(err)
+		return query
+	}
+
+	for _, key := range keys {
+		column, ok := sortColumns[key.Field]
+		if !ok {
+			_ = query.AddError(fmt.Errorf("%w: unknown sort field %q", server.ErrInvalidSort, key.Field))
+			return query
+		}
+		order := "ASC"
+		if key.Desc {
+			order = "DESC"
+		}
+		query = query.Order(column + " " + order + " NULLS LAST")
+	}
+	return query
 }
 
 func (r *gormServerRepository) applyPagination(query *gorm.DB, pagination server.ServerPagination) *gorm.DB {
@@ -234,3 +718,15 @@ func (r *gormServerRepository) ExistsWithName(ctx context.Context, name string)
 	}
 	return count > 0, nil
 }
+
+// chunk returns up to size values of values starting at start
+func chunk(values []string, start, size int) []string {
+	if start >= len(values) {
+		return nil
+	}
+	end := start + size
+	if end > len(values) {
+		end = len(values)
+	}
+	return values[start:end]
+}
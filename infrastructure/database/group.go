@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/lits-06/vcs-sms/usecases/server"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormGroupRepository struct {
+	db *gorm.DB
+}
+
+// NewGroupRepository creates a new GORM server group repository
+func NewGroupRepository(db *gorm.DB) server.GroupRepository {
+	return &gormGroupRepository{
+		db: db,
+	}
+}
+
+func (r *gormGroupRepository) CreateGroup(ctx context.Context, group *entity.ServerGroup) error {
+	if err := r.db.WithContext(ctx).Create(group).Error; err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+	return nil
+}
+
+func (r *gormGroupRepository) GetGroup(ctx context.Context, id string) (*entity.ServerGroup, error) {
+	return r.getGroup(ctx, "id = ?", id)
+}
+
+func (r *gormGroupRepository) GetGroupByName(ctx context.Context, name string) (*entity.ServerGroup, error) {
+	return r.getGroup(ctx, "name = ?", name)
+}
+
+func (r *gormGroupRepository) getGroup(ctx context.Context, condition string, value string) (*entity.ServerGroup, error) {
+	var group entity.ServerGroup
+	err := r.db.WithContext(ctx).Where(condition, value).First(&group).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	groups := []entity.ServerGroup{group}
+	if err := r.loadMembers(ctx, groups); err != nil {
+		return nil, err
+	}
+	return &groups[0], nil
+}
+
+func (r *gormGroupRepository) ListGroups(ctx context.Context) ([]entity.ServerGroup, error) {
+	var groups []entity.ServerGroup
+	if err := r.db.WithContext(ctx).Order("name").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	if err := r.loadMembers(ctx, groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// loadMembers fills in the ServerIDs of groups
+func (r *gormGroupRepository) loadMembers(ctx context.Context, groups []entity.ServerGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	ids := make([]string, len(groups))
+	for i, group := range groups {
+		ids[i] = group.ID
+	}
+
+	var members []entity.ServerGroupMember
+	err := r.db.WithContext(ctx).Where("group_id IN ?", ids).Order("server_id").Find(&members).Error
+	if err != nil {
+		return fmt.Errorf("failed to load group members: %w", err)
+	}
+	byGroup := make(map[string][]string, len(groups))
+	for _, member := range members {
+		byGroup[member.GroupID] = append(byGroup[member.GroupID], member.ServerID)
+	}
+	for i := range groups {
+		groups[i].ServerIDs = byGroup[groups[i].ID]
+		if groups[i].ServerIDs == nil {
+			groups[i].ServerIDs = []string{}
+		}
+	}
+	return nil
+}
+
+func (r *gormGroupRepository) UpdateGroup(ctx context.Context, group *entity.ServerGroup) error {
+	// The description may be cleared, so the columns are listed explicitly
+	err := r.db.WithContext(ctx).Model(group).Select("name", "description", "updated_at").Updates(group).Error
+	if err != nil {
+		return fmt.Errorf("failed to update group: %w", err)
+	}
+	return nil
+}
+
+func (r *gormGroupRepository) DeleteGroup(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.ServerGroup{}).Error; err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+	return nil
+}
+
+func (r *gormGroupRepository) AddMembers(ctx context.Context, groupID string, serverIDs []string) error {
+	if len(serverIDs) == 0 {
+		return nil
+	}
+	members := make([]entity.ServerGroupMember, len(serverIDs))
+	for i, serverID := range serverIDs {
+		members[i] = entity.ServerGroupMember{GroupID: groupID, ServerID: serverID}
+	}
+
+	err := r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(members, lookupChunkSize).Error
+	if err != nil {
+		return fmt.Errorf("failed to add group members: %w", err)
+	}
+	return nil
+}
+
+func (r *gormGroupRepository) RemoveMembers(ctx context.Context, groupID string, serverIDs []string) error {
+	for start := 0; start < len(serverIDs); start += lookupChunkSize {
+		err := r.db.WithContext(ctx).
+			Where("group_id = ? AND server_id IN ?", groupID, chunk(serverIDs, start, lookupChunkSize)).
+			Delete(&entity.ServerGroupMember{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to remove group members: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *gormGroupRepository) Transaction(ctx context.Context, fn func(groups server.GroupRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormGroupRepository{db: tx})
+	})
+}
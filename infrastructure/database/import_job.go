@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/lits-06/vcs-sms/usecases/server"
+	"gorm.io/gorm"
+)
+
+type gormImportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewImportJobRepository creates a new GORM import job repository
+func NewImportJobRepository(db *gorm.DB) server.ImportJobRepository {
+	return &gormImportJobRepository{
+		db: db,
+	}
+}
+
+func (r *gormImportJobRepository) CreateJob(ctx context.Context, job *entity.ImportJob) error {
+	if err := r.db.WithContext(ctx).Create(job).Error; err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+	return nil
+}
+
+// importJobStatusColumns are the columns of a job shown when polling it, without the file and report blobs
+var importJobStatusColumns = []string{
+	"id", "status", "filename", "format", "mode", "dry_run", "atomic",
+	"total_rows", "processed_rows", "succeeded_rows", "failed_rows",
+	"result", "error", "created_at", "started_at", "finished_at",
+}
+
+func (r *gormImportJobRepository) GetJob(ctx context.Context, id string) (*entity.ImportJob, error) {
+	var job entity.ImportJob
+	err := r.db.WithContext(ctx).Select(importJobStatusColumns).Where("id = ?", id).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+	return &job, nil
+}
+
+func (r *gormImportJobRepository) GetJobData(ctx context.Context, id string) ([]byte, error) {
+	var job entity.ImportJob
+	err := r.db.WithContext(ctx).Select("data").Where("id = ?", id).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get import job data: %w", err)
+	}
+	return job.Data, nil
+}
+
+func (r *gormImportJobRepository) GetJobReport(ctx context.Context, id string) ([]byte, error) {
+	var job entity.ImportJob
+	err := r.db.WithContext(ctx).Select("report").Where("id = ?", id).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get import job report: %w", err)
+	}
+	return job.Report, nil
+}
+
+func (r *gormImportJobRepository) UpdateJob(ctx context.Context, job *entity.ImportJob) error {
+	// Zero values such as a cleared file must be written too, so the columns are listed explicitly
+	err := r.db.WithContext(ctx).Model(job).
+		Select("status", "total_rows", "processed_rows", "succeeded_rows", "failed_rows",
+			"result", "error", "data", "report", "started_at", "finished_at").
+		Updates(job).Error
+	if err != nil {
+		return fmt.Errorf("failed to update import job: %w", err)
+	}
+	return nil
+}
+
+func (r *gormImportJobRepository) ListJobsByStatus(ctx context.Context, status entity.ImportJobStatus) ([]entity.ImportJob, error) {
+	var jobs []entity.ImportJob
+	err := r.db.WithContext(ctx).Omit("data", "report").Where("status = ?", status).Order("created_at").Find(&jobs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import jobs: %w", err)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+	infraServer "github.com/lits-06/vcs-sms/infrastructure/server"
+	"gorm.io/gorm"
+)
+
+type gormPortRepository struct {
+	db *gorm.DB
+}
+
+// NewPortRepository creates a new GORM store for server port assignments
+func NewPortRepository(db *gorm.DB) infraServer.PortStore {
+	return &gormPortRepository{
+		db: db,
+	}
+}
+
+// NewProcessRepository creates a new GORM store for the processes of servers, kept with their port assignments
+func NewProcessRepository(db *gorm.DB) infraServer.ProcessStore {
+	return &gormPortRepository{
+		db: db,
+	}
+}
+
+func (r *gormPortRepository) GetPort(ctx context.Context, serverID string) (int, bool, error) {
+	var assignment entity.PortAssignment
+	err := r.db.WithContext(ctx).Where("server_id = ?", serverID).First(&assignment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get port assignment: %w", err)
+	}
+	return assignment.Port, true, nil
+}
+
+func (r *gormPortRepository) ListPorts(ctx context.Context) (map[int]string, error) {
+	var assignments []entity.PortAssignment
+	if err := r.db.WithContext(ctx).Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list port assignments: %w", err)
+	}
+
+	ports := make(map[int]string, len(assignments))
+	for _, assignment := range assignments {
+		ports[assignment.Port] = assignment.ServerID
+	}
+	return ports, nil
+}
+
+func (r *gormPortRepository) AssignPort(ctx context.Context, serverID string, port int) error {
+	assignment := &entity.PortAssignment{
+		ServerID:  serverID,
+		Port:      port,
+		CreatedAt: time.Now(),
+	}
+	// The unique index on port rejects concurrent assignments of the same port
+	if err := r.db.WithContext(ctx).Create(assignment).Error; err != nil {
+		return fmt.Errorf("failed to create port assignment: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPortRepository) ReleasePort(ctx context.Context, serverID string) error {
+	err := r.db.WithContext(ctx).Where("server_id = ?", serverID).Delete(&entity.PortAssignment{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete port assignment: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPortRepository) GetProcess(ctx context.Context, serverID string) (int, time.Time, error) {
+	var assignment entity.PortAssignment
+	err := r.db.WithContext(ctx).Select("pid", "started_at").Where("server_id = ?", serverID).First(&assignment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, fmt.Errorf("failed to get server process: %w", err)
+	}
+	if assignment.PID == 0 || assignment.StartedAt == nil {
+		return 0, time.Time{}, nil
+	}
+	return assignment.PID, *assignment.StartedAt, nil
+}
+
+func (r *gormPortRepository) SaveProcess(ctx context.Context, serverID string, pid int, startedAt time.Time) error {
+	var started *time.Time
+	if pid != 0 {
+		started = &startedAt
+	}
+	err := r.db.WithContext(ctx).Model(&entity.PortAssignment{}).Where("server_id = ?", serverID).
+		Updates(map[string]interface{}{"pid": pid, "started_at": started}).Error
+	if err != nil {
+		return fmt.Errorf("failed to save server process: %w", err)
+	}
+	return nil
+}
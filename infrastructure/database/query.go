@@ -0,0 +1,131 @@
+package database
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lits-06/vcs-sms/usecases/server"
+)
+
+// queryColumns maps the text, status and time fields of a query to their column
+var queryColumns = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"provider":    "provider",
+	"description": "description",
+	"environment": "environment",
+	"location":    "location",
+	"owner":       "owner",
+	"status":      "status",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
+// queryOperators maps the ordering operators of a query to SQL
+var queryOperators = map[server.QueryOperator]string{
+	server.QueryGreater:        ">",
+	server.QueryGreaterOrEqual: ">=",
+	server.QueryLess:           "<",
+	server.QueryLessOrEqual:    "<=",
+}
+
+// compileQuery turns a parsed query into a WHERE condition. Every value is a bind
+// parameter and every column comes from a whitelist, so the condition is safe to run.
+func compileQuery(expr server.QueryExpr) (string, []interface{}) {
+	switch expr := expr.(type) {
+	case *server.QueryAnd:
+		return compileQueryOperands(expr.Operands, " AND ")
+	case *server.QueryOr:
+		return compileQueryOperands(expr.Operands, " OR ")
+	case *server.QueryNot:
+		condition, args := compileQuery(expr.Operand)
+		return "NOT " + condition, args
+	case *server.QueryComparison:
+		condition, args := compileQueryComparison(expr)
+		return "(" + condition + ")", args
+	default:
+		return "FALSE", nil
+	}
+}
+
+func compileQueryOperands(operands []server.QueryExpr, separator string) (string, []interface{}) {
+	conditions := make([]string, 0, len(operands))
+	var args []interface{}
+	for _, operand := range operands {
+		condition, operandArgs := compileQuery(operand)
+		conditions = append(conditions, condition)
+		args = append(args, operandArgs...)
+	}
+	return "(" + strings.Join(conditions, separator) + ")", args
+}
+
+func compileQueryComparison(c *server.QueryComparison) (string, []interface{}) {
+	negate := func(condition string, args []interface{}) (string, []interface{}) {
+		if c.Operator == server.QueryNotEquals {
+			return "NOT (" + condition + ")", args
+		}
+		return condition, args
+	}
+
+	switch c.Kind {
+	case server.QueryText:
+		column, ok := queryColumns[c.Field]
+		if !ok {
+			return "FALSE", nil
+		}
+		switch {
+		case c.Operator == server.QueryNotEquals:
+			return column + " <> ?", []interface{}{c.Value}
+		case c.Operator == server.QueryMatch:
+			return column + ` ILIKE ? ESCAPE '\'`, []interface{}{c.Pattern()}
+		default:
+			return column + " = ?", []interface{}{c.Value}
+		}
+	case server.QueryStatus:
+		if c.Operator == server.QueryNotEquals {
+			return "status <> ?", []interface{}{c.Value}
+		}
+		return "status = ?", []interface{}{c.Value}
+	case server.QueryIPv4:
+		if len(c.IPRanges) == 0 {
+			return "FALSE", nil
+		}
+		return negate(ipRangesCondition(c.IPRanges))
+	case server.QueryIPv6:
+		if len(c.Prefixes) == 0 {
+			return "FALSE", nil
+		}
+		return negate(ipv6PrefixesCondition(c.Prefixes))
+	case server.QueryMAC:
+		if len(c.MACs) == 0 {
+			return "FALSE", nil
+		}
+		return negate(interfaceCondition("ni.mac IN ?"), []interface{}{c.MACs})
+	case server.QueryTime:
+		column, ok := queryColumns[c.Field]
+		if !ok {
+			return "FALSE", nil
+		}
+		if operator, ok := queryOperators[c.Operator]; ok {
+			return column + " " + operator + " ?", []interface{}{c.Time}
+		}
+		if !c.Until.IsZero() {
+			return negate(column+" >= ? AND "+column+" < ?", []interface{}{c.Time, c.Until})
+		}
+		return negate(column+" = ?", []interface{}{c.Time})
+	case server.QueryLabel:
+		if c.Value == "*" {
+			if c.Operator == server.QueryNotEquals {
+				return "labels ->> ? IS NULL", []interface{}{c.Label}
+			}
+			return "labels ->> ? IS NOT NULL", []interface{}{c.Label}
+		}
+		if c.Operator == server.QueryNotEquals {
+			return "(labels ->> ?) IS DISTINCT FROM ?", []interface{}{c.Label, c.Value}
+		}
+		contained, _ := json.Marshal(map[string]string{c.Label: c.Value})
+		return "labels @> ?::jsonb", []interface{}{string(contained)}
+	default:
+		return "FALSE", nil
+	}
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/lits-06/vcs-sms/usecases/server"
+)
+
+// searchMigrations add the generated columns and indexes behind server search.
+// search_vector weighs name and ID above addresses, metadata and description;
+// search_text holds every searchable field for trigram matching.
+var searchMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`ALTER TABLE servers ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple'::regconfig, coalesce(name, '') || ' ' || coalesce(id, '')), 'A') ||
+		setweight(to_tsvector('simple'::regconfig, coalesce(host(ipv4), '') || ' ' || coalesce(host(ipv6), '')), 'B') ||
+		setweight(to_tsvector('simple'::regconfig, coalesce(environment, '') || ' ' || coalesce(location, '') || ' ' ||
+			coalesce(owner, '') || ' ' || coalesce(labels::text, '')), 'C') ||
+		setweight(to_tsvector('english'::regconfig, coalesce(description, '')), 'D')
+	) STORED`,
+	`ALTER TABLE servers ADD COLUMN IF NOT EXISTS search_text text GENERATED ALWAYS AS (
+		lower(coalesce(id, '') || ' ' || coalesce(name, '') || ' ' || coalesce(host(ipv4), '') || ' ' ||
+			coalesce(host(ipv6), '') || ' ' || coalesce(environment, '') || ' ' || coalesce(location, '') || ' ' ||
+			coalesce(owner, '') || ' ' || coalesce(description, '') || ' ' || coalesce(labels::text, ''))
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_servers_search_vector ON servers USING gin (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_servers_search_text ON servers USING gin (search_text gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_servers_name_trgm ON servers USING gin (name gin_trgm_ops)`,
+}
+
+// Search ranks servers by full-text relevance, with prefix matching on words, plus trigram
+// word similarity, which tolerates typos. Exact name or ID matches come first.
+func (r *gormServerRepository) Search(ctx context.Context, query string, limit int) ([]server.ServerSearchResult, error) {
+	q := strings.ToLower(strings.TrimSpace(query))
+	args := map[string]interface{}{
+		"q":     q,
+		"like":  "%" + escapeLike(q) + "%",
+		"limit": limit,
+	}
+
+	conditions := []string{"@q <% search_text", "search_text LIKE @like"}
+	rank := "word_similarity(@q, search_text) + CASE WHEN lower(name) = @q OR lower(id) = @q THEN 1 ELSE 0 END"
+	if tsquery := prefixTSQuery(q); tsquery != "" {
+		args["tsquery"] = tsquery
+		conditions = append(conditions, "search_vector @@ to_tsquery('simple', @tsquery)")
+		rank += " + 2 * ts_rank(search_vector, to_tsquery('simple', @tsquery))"
+	}
+
+	var ranked []struct {
+		ID   string
+		Rank float64
+	}
+	err := r.db.WithContext(ctx).Raw(`SELECT id, `+rank+` AS rank FROM servers
+		WHERE `+strings.Join(conditions, " OR ")+`
+		ORDER BY rank DESC, name
+		LIMIT @limit`, args).Scan(&ranked).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to search servers: %w", err)
+	}
+	if len(ranked) == 0 {
+		return []server.ServerSearchResult{}, nil
+	}
+
+	ids := make([]string, len(ranked))
+	for i, row := range ranked {
+		ids[i] = row.ID
+	}
+	var servers []entity.Server
+	if err := preloadInterfaces(r.db.WithContext(ctx)).Where("id IN ?", ids).Find(&servers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load search results: %w", err)
+	}
+	byID := make(map[string]entity.Server, len(servers))
+	for _, srv := range servers {
+		byID[srv.ID] = srv
+	}
+
+	results := make([]server.ServerSearchResult, 0, len(ranked))
+	for _, row := range ranked {
+		if srv, ok := byID[row.ID]; ok {
+			results = append(results, server.ServerSearchResult{Server: srv, Score: row.Rank})
+		}
+	}
+	return results, nil
+}
+
+// prefixTSQuery turns the words of q into a tsquery matching words starting with each of them
+func prefixTSQuery(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = word + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// escapeLike escapes the LIKE wildcards of s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
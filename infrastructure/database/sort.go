@@ -0,0 +1,29 @@
+package database
+
+// sortMigrations add natural_sort_key, which pads the digit runs of a text with zeros and lower-cases
+// the rest so that plain text ordering of the keys is natural ordering, and an index of the name keys
+var sortMigrations = []string{
+	`CREATE OR REPLACE FUNCTION natural_sort_key(value text) RETURNS text
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+			SELECT string_agg(CASE WHEN m.part[1] ~ '^[0-9]'
+				THEN lpad(m.part[1], greatest(20, length(m.part[1])), '0')
+				ELSE lower(m.part[1]) END, '' ORDER BY m.n)
+			FROM regexp_matches(value, '[0-9]+|[^0-9]+', 'g') WITH ORDINALITY AS m(part, n)
+		$$`,
+	`CREATE INDEX IF NOT EXISTS idx_servers_name_natural ON servers (natural_sort_key(name))`,
+}
+
+// sortColumns maps the sortable fields to the expressions servers are ordered by
+var sortColumns = map[string]string{
+	"id":          "id",
+	"name":        "natural_sort_key(name)",
+	"status":      "status",
+	"ipv4":        "ipv4", // inet orders numerically
+	"ipv6":        "ipv6",
+	"provider":    "provider",
+	"environment": "environment",
+	"location":    "location",
+	"owner":       "owner",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/lits-06/vcs-sms/usecases/server"
+	"gorm.io/gorm"
+)
+
+// Stats runs one GROUP BY query per aggregate, each restricted by filter
+func (r *gormServerRepository) Stats(ctx context.Context, filter server.ServerFilter, loc *time.Location) (*server.ServerStats, error) {
+	stats := &server.ServerStats{
+		ByStatus:      make(map[string]int),
+		ByEnvironment: make(map[string]int),
+		ByLabel:       make(map[string][]server.StatsCount),
+	}
+	filtered := func() *gorm.DB {
+		return r.applyFilters(r.db.WithContext(ctx).Model(&entity.Server{}), filter)
+	}
+
+	var byStatus []server.StatsCount
+	err := filtered().Select("status AS value, count(*) AS count").Group("status").Scan(&byStatus).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count servers by status: %w", err)
+	}
+	for _, row := range byStatus {
+		stats.ByStatus[row.Value] = row.Count
+		stats.Total += row.Count
+	}
+
+	var byEnvironment []server.StatsCount
+	err = filtered().Select("coalesce(environment, '') AS value, count(*) AS count").
+		Group("coalesce(environment, '')").Scan(&byEnvironment).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count servers by environment: %w", err)
+	}
+	for _, row := range byEnvironment {
+		stats.ByEnvironment[row.Value] += row.Count
+	}
+
+	stats.BySubnet = []server.StatsCount{}
+	err = filtered().Select("network(set_masklen(ipv4, 24))::text AS value, count(*) AS count").
+		Where("ipv4 IS NOT NULL").Group("value").Order("count DESC, value").Scan(&stats.BySubnet).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count servers by subnet: %w", err)
+	}
+
+	var byLabel []struct {
+		Key   string
+		Value string
+		Count int
+	}
+	// Servers without labels may hold a JSON null, which jsonb_each_text rejects
+	err = filtered().
+		Joins("CROSS JOIN LATERAL jsonb_each_text(CASE WHEN jsonb_typeof(servers.labels) = 'object' THEN servers.labels ELSE '{}' END) AS label").
+		Select("label.key AS key, label.value AS value, count(*) AS count").
+		Group("label.key, label.value").Order("key, count DESC, value").Scan(&byLabel).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count servers by label: %w", err)
+	}
+	for _, row := range byLabel {
+		stats.ByLabel[row.Key] = append(stats.ByLabel[row.Key], server.StatsCount{Value: row.Value, Count: row.Count})
+	}
+
+	if stats.CreatedPerDay, err = r.creationHistogram(filtered(), "day", loc); err != nil {
+		return nil, err
+	}
+	if stats.CreatedPerWeek, err = r.creationHistogram(filtered(), "week", loc); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// creationHistogram counts the servers of query created in each period, truncating
+// creation times to the unit in the time zone loc
+func (r *gormServerRepository) creationHistogram(query *gorm.DB, unit string, loc *time.Location) ([]server.StatsBucket, error) {
+	var rows []struct {
+		Start time.Time
+		Count int
+	}
+	bucket := "date_trunc('" + unit + "', created_at AT TIME ZONE ?) AT TIME ZONE ?"
+	err := query.Select(bucket+" AS start, count(*) AS count", loc.String(), loc.String()).
+		Group("start").Order("start").Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count servers created per %s: %w", unit, err)
+	}
+
+	buckets := make([]server.StatsBucket, len(rows))
+	for i, row := range rows {
+		buckets[i] = server.StatsBucket{Start: row.Start.In(loc), Count: row.Count}
+	}
+	return buckets, nil
+}
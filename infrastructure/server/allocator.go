@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPortRangeExhausted is returned when every port in the configured range is taken
+var ErrPortRangeExhausted = errors.New("port range exhausted")
+
+// PortStore persists port assignments so servers keep their port across restarts
+type PortStore interface {
+	GetPort(ctx context.Context, serverID string) (int, bool, error)
+	ListPorts(ctx context.Context) (map[int]string, error) // port -> server ID
+	AssignPort(ctx context.Context, serverID string, port int) error
+	ReleasePort(ctx context.Context, serverID string) error
+}
+
+// PortAllocator hands out ports from a fixed range and remembers them in a PortStore.
+// Assignments are cached after the first allocation, so bulk imports do not reload them per server.
+type PortAllocator struct {
+	min      int
+	max      int
+	reserved map[int]bool
+	store    PortStore
+	mu       sync.Mutex
+
+	ports   map[int]string // port -> server ID, nil until loaded
+	servers map[string]int // server ID -> port
+}
+
+// NewPortAllocator creates an allocator for ports in [min, max].
+// Reserved ports, such as the main API port, are never handed out.
+func NewPortAllocator(min, max int, reserved []int, store PortStore) (*PortAllocator, error) {
+	if min < 1024 || max > 65535 || min > max {
+		return nil, fmt.Errorf("invalid port range %d-%d", min, max)
+	}
+
+	reservedPorts := make(map[int]bool, len(reserved))
+	available := max - min + 1
+	for _, port := range reserved {
+		if port >= min && port <= max && !reservedPorts[port] {
+			available--
+		}
+		reservedPorts[port] = true
+	}
+	if available == 0 {
+		return nil, fmt.Errorf("port range %d-%d only contains reserved ports", min, max)
+	}
+
+	return &PortAllocator{
+		min:      min,
+		max:      max,
+		reserved: reservedPorts,
+		store:    store,
+	}, nil
+}
+
+// Allocate returns the port assigned to serverID, assigning a free one if needed.
+// A previously persisted port is reused as long as it is still inside the range.
+func (a *PortAllocator) Allocate(ctx context.Context, serverID string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.load(ctx); err != nil {
+		return 0, err
+	}
+
+	if port, exists := a.servers[serverID]; exists {
+		if a.usable(port) {
+			return port, nil
+		}
+		// Range or reserved ports changed since the assignment was made
+		if err := a.store.ReleasePort(ctx, serverID); err != nil {
+			return 0, fmt.Errorf("failed to release port assignment: %w", err)
+		}
+		a.forget(serverID)
+	}
+
+	for port := a.min; port <= a.max; port++ {
+		if a.reserved[port] {
+			continue
+		}
+		if _, taken := a.ports[port]; taken {
+			continue
+		}
+		if isPortInUse(port) {
+			continue
+		}
+
+		if err := a.store.AssignPort(ctx, serverID, port); err != nil {
+			a.ports, a.servers = nil, nil // Another instance may have taken the port, reload next time
+			return 0, fmt.Errorf("failed to assign port %d: %w", port, err)
+		}
+		a.ports[port] = serverID
+		a.servers[serverID] = port
+		return port, nil
+	}
+
+	return 0, fmt.Errorf("no free port in range %d-%d: %w", a.min, a.max, ErrPortRangeExhausted)
+}
+
+// Lookup returns the port persisted for serverID without assigning a new one
+func (a *PortAllocator) Lookup(ctx context.Context, serverID string) (int, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.servers != nil {
+		port, exists := a.servers[serverID]
+		return port, exists, nil
+	}
+	return a.store.GetPort(ctx, serverID)
+}
+
+// Release frees the port assigned to serverID
+func (a *PortAllocator) Release(ctx context.Context, serverID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.store.ReleasePort(ctx, serverID); err != nil {
+		return err
+	}
+	a.forget(serverID)
+	return nil
+}
+
+// load caches the persisted assignments if they are not cached yet
+func (a *PortAllocator) load(ctx context.Context) error {
+	if a.ports != nil {
+		return nil
+	}
+
+	assigned, err := a.store.ListPorts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list port assignments: %w", err)
+	}
+
+	if assigned == nil {
+		assigned = make(map[int]string)
+	}
+	a.ports = assigned
+	a.servers = make(map[string]int, len(assigned))
+	for port, serverID := range assigned {
+		a.servers[serverID] = port
+	}
+	return nil
+}
+
+func (a *PortAllocator) forget(serverID string) {
+	if port, exists := a.servers[serverID]; exists {
+		delete(a.ports, port)
+		delete(a.servers, serverID)
+	}
+}
+
+func (a *PortAllocator) usable(port int) bool {
+	return port >= a.min && port <= a.max && !a.reserved[port]
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/lits-06/vcs-sms/usecases/server"
+)
+
+// ExternalServerProvider implements ServerProvider interface
+// for servers managed outside this system. It never starts or stops anything,
+// it only health-checks the server's address.
+type ExternalServerProvider struct {
+	port    int
+	timeout time.Duration
+	servers server.Repository // source of the addresses of servers created before a restart
+
+	mu        sync.RWMutex
+	addresses map[string]string // server ID -> address of the primary interface
+}
+
+// NewExternalServerProvider creates a provider that health-checks servers by
+// opening a TCP connection to the address of their primary interface on port.
+// Addresses not seen since startup are read from servers.
+func NewExternalServerProvider(port int, timeout time.Duration, servers server.Repository) *ExternalServerProvider {
+	if port == 0 {
+		port = 80
+	}
+	if timeout == 0 {
+		timeout = 3 * time.Second
+	}
+
+	return &ExternalServerProvider{
+		port:      port,
+		timeout:   timeout,
+		servers:   servers,
+		addresses: make(map[string]string),
+	}
+}
+
+// CreateServer records the server's address for health checks
+func (p *ExternalServerProvider) CreateServer(ctx context.Context, srv *entity.Server) error {
+	p.mu.Lock()
+	p.addresses[srv.ID] = srv.PrimaryAddress()
+	p.mu.Unlock()
+	return nil
+}
+
+// UpdateServer refreshes the server's address; status changes are ignored
+func (p *ExternalServerProvider) UpdateServer(ctx context.Context, srv *entity.Server) error {
+	return p.CreateServer(ctx, srv)
+}
+
+// DeleteServer forgets the server
+func (p *ExternalServerProvider) DeleteServer(ctx context.Context, serverID string) error {
+	p.mu.Lock()
+	delete(p.addresses, serverID)
+	p.mu.Unlock()
+	return nil
+}
+
+// StartServer always fails, external servers are not managed by us
+func (p *ExternalServerProvider) StartServer(ctx context.Context, serverID string) error {
+	return fmt.Errorf("failed to start server %s: %w", serverID, server.ErrUnmanagedServer)
+}
+
+// StopServer always fails, external servers are not managed by us
+func (p *ExternalServerProvider) StopServer(ctx context.Context, serverID string) error {
+	return fmt.Errorf("failed to stop server %s: %w", serverID, server.ErrUnmanagedServer)
+}
+
+// GetServerStatus reports ON when the server accepts TCP connections on the health check port
+func (p *ExternalServerProvider) GetServerStatus(ctx context.Context, serverID string) (entity.ServerStatus, error) {
+	address, err := p.address(ctx, serverID)
+	if err != nil {
+		return entity.StatusOffline, err
+	}
+
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(p.port)))
+	if err != nil {
+		return entity.StatusOffline, nil
+	}
+	conn.Close()
+
+	return entity.StatusOnline, nil
+}
+
+// address returns the address of the server's primary interface, loading it from
+// the repository when the server has not been created or updated since startup
+func (p *ExternalServerProvider) address(ctx context.Context, serverID string) (string, error) {
+	p.mu.RLock()
+	address, exists := p.addresses[serverID]
+	p.mu.RUnlock()
+	if exists {
+		return address, nil
+	}
+
+	srv, err := p.servers.GetByID(ctx, serverID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up address of server %s: %w", serverID, err)
+	}
+	if srv == nil {
+		return "", fmt.Errorf("server %s not found", serverID)
+	}
+
+	p.mu.Lock()
+	p.addresses[serverID] = srv.PrimaryAddress()
+	p.mu.Unlock()
+	return srv.PrimaryAddress(), nil
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lits-06/vcs-sms/usecases/server"
+)
+
+// FaultInjector implements server.FaultInjector for the simulated servers of PortServerProvider
+type FaultInjector struct {
+	mu     sync.RWMutex
+	faults map[string]*server.Fault
+	seq    int
+
+	// crash is set by the provider to schedule crashes of its running servers
+	crash func(fault server.Fault)
+}
+
+// NewFaultInjector creates an injector with no active faults
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{
+		faults: make(map[string]*server.Fault),
+	}
+}
+
+// InjectFault validates and activates a fault
+func (f *FaultInjector) InjectFault(ctx context.Context, req server.InjectFaultRequest) (*server.Fault, error) {
+	switch req.Kind {
+	case server.FaultSlow:
+		if req.DelayMs <= 0 {
+			return nil, fmt.Errorf("%w: delay_ms is required for slow faults", server.ErrInvalidFault)
+		}
+	case server.FaultError, server.FaultDrop, server.FaultCrash:
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", server.ErrInvalidFault, req.Kind)
+	}
+
+	if (len(req.ServerIDs) == 0) == (req.Percentage == 0) {
+		return nil, fmt.Errorf("%w: exactly one of server_ids or percentage is required", server.ErrInvalidFault)
+	}
+	if req.Percentage < 0 || req.Percentage > 100 {
+		return nil, fmt.Errorf("%w: percentage must be between 1 and 100", server.ErrInvalidFault)
+	}
+
+	f.mu.Lock()
+	f.seq++
+	fault := &server.Fault{
+		ID:         fmt.Sprintf("fault-%d", f.seq),
+		Kind:       req.Kind,
+		ServerIDs:  req.ServerIDs,
+		Percentage: req.Percentage,
+		Delay:      time.Duration(req.DelayMs) * time.Millisecond,
+		After:      time.Duration(req.AfterSeconds) * time.Second,
+		CreatedAt:  time.Now(),
+	}
+	if req.DurationSeconds > 0 {
+		expiresAt := fault.CreatedAt.Add(time.Duration(req.DurationSeconds) * time.Second)
+		fault.ExpiresAt = &expiresAt
+		time.AfterFunc(time.Until(expiresAt), func() {
+			_ = f.ClearFault(context.Background(), fault.ID)
+		})
+	}
+	f.faults[fault.ID] = fault
+	crash := f.crash
+	f.mu.Unlock()
+
+	if fault.Kind == server.FaultCrash && crash != nil {
+		crash(*fault)
+	}
+
+	return fault, nil
+}
+
+// ListFaults returns the active faults ordered by creation
+func (f *FaultInjector) ListFaults(ctx context.Context) []server.Fault {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	faults := make([]server.Fault, 0, len(f.faults))
+	for _, fault := range f.faults {
+		faults = append(faults, *fault)
+	}
+	sort.Slice(faults, func(i, j int) bool {
+		return faults[i].CreatedAt.Before(faults[j].CreatedAt)
+	})
+	return faults
+}
+
+// ClearFault deactivates a fault. Pending crashes of that fault are cancelled.
+func (f *FaultInjector) ClearFault(ctx context.Context, faultID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.faults[faultID]; !exists {
+		return fmt.Errorf("%w: %s", server.ErrFaultNotFound, faultID)
+	}
+	delete(f.faults, faultID)
+	return nil
+}
+
+// ClearFaults deactivates every fault
+func (f *FaultInjector) ClearFaults(ctx context.Context) {
+	f.mu.Lock()
+	f.faults = make(map[string]*server.Fault)
+	f.mu.Unlock()
+}
+
+// Helper methods
+
+// active reports whether a fault is still injected
+func (f *FaultInjector) active(faultID string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, exists := f.faults[faultID]
+	return exists
+}
+
+// matching returns the active faults of the given kind that target serverID
+func (f *FaultInjector) matching(serverID string, kind server.FaultKind) []server.Fault {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var faults []server.Fault
+	for _, fault := range f.faults {
+		if fault.Kind == kind && targets(*fault, serverID) {
+			faults = append(faults, *fault)
+		}
+	}
+	return faults
+}
+
+// targets reports whether fault applies to serverID. Percentage faults pick a
+// stable pseudo-random subset of the fleet by hashing the fault and server IDs.
+func targets(fault server.Fault, serverID string) bool {
+	if fault.Percentage > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(fault.ID + "/" + serverID))
+		return int(h.Sum32()%100) < fault.Percentage
+	}
+
+	for _, id := range fault.ServerIDs {
+		if id == serverID {
+			return true
+		}
+	}
+	return false
+}
+
+// middleware applies slow, drop and error faults to a simulated server's handler
+func (f *FaultInjector) middleware(serverID string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(f.matching(serverID, server.FaultDrop)) > 0 {
+			if hijacker, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hijacker.Hijack(); err == nil {
+					conn.Close()
+					return
+				}
+			}
+			panic(http.ErrAbortHandler)
+		}
+
+		for _, fault := range f.matching(serverID, server.FaultSlow) {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if r.URL.Path == "/status" && len(f.matching(serverID, server.FaultError)) > 0 {
+			http.Error(w, `{"error": "injected fault"}`, http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
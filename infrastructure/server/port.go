@@ -5,56 +5,89 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/lits-06/vcs-sms/entity"
+	"github.com/lits-06/vcs-sms/usecases/server"
 )
 
 // PortServerProvider implements ServerProvider interface
 // This provider manages servers by starting/stopping services on specific ports
 type PortServerProvider struct {
+	allocator *PortAllocator
+	faults    *FaultInjector    // optional, nil disables fault injection
+	servers   server.Repository // source of the profiles of servers created before a restart
+
+	mu            sync.RWMutex
 	activeServers map[string]*ServerProcess
 }
 
-// ServerProcess represents a running server process
+// ServerProcess represents a running server process.
+// mu guards the fields below it, which change as the server is started, stopped or crashed.
 type ServerProcess struct {
-	ServerID   string
-	Host       string
-	Port       int
+	ServerID string
+	Host     string
+	Port     int
+
+	mu         sync.Mutex
 	HTTPServer *http.Server // HTTP server instance for Golang implementation
 	Status     entity.ServerStatus
+	Profile    *entity.SimulationProfile
+	simulation *simulatedServer // handler of the running HTTP server
 }
 
-// NewPortServerProvider creates a new instance of PortServerProvider
-func NewPortServerProvider() *PortServerProvider {
-	return &PortServerProvider{
+// NewPortServerProvider creates a new instance of PortServerProvider.
+// When faults is not nil, its active faults are applied to the simulated servers.
+// Profiles of servers not seen since startup are read from servers.
+func NewPortServerProvider(allocator *PortAllocator, faults *FaultInjector, servers server.Repository) *PortServerProvider {
+	p := &PortServerProvider{
+		allocator:     allocator,
+		faults:        faults,
+		servers:       servers,
 		activeServers: make(map[string]*ServerProcess),
 	}
+
+	if faults != nil {
+		faults.mu.Lock()
+		faults.crash = p.scheduleCrash
+		faults.mu.Unlock()
+	}
+
+	return p
 }
 
-// CreateServer creates a new server by automatically finding an available port
+// CreateServer creates a new server on a port taken from the allocator
 func (p *PortServerProvider) CreateServer(ctx context.Context, server *entity.Server) error {
-	// Find an available port automatically
-	availablePort, err := p.findAvailablePort()
+	// Reuse the persisted port or allocate a new one from the configured range
+	port, err := p.allocator.Allocate(ctx, server.ID)
 	if err != nil {
-		return fmt.Errorf("failed to find available port: %w", err)
+		return fmt.Errorf("failed to allocate port: %w", err)
 	}
 
 	// Store server info but don't start it yet
+	p.mu.Lock()
 	p.activeServers[server.ID] = &ServerProcess{
 		ServerID:   server.ID,
 		Host:       "localhost", // Always use localhost
-		Port:       availablePort,
+		Port:       port,
 		HTTPServer: nil,
 		Status:     entity.StatusOffline, // Always start as offline
+		Profile:    server.Profile,
 	}
+	p.mu.Unlock()
 
 	// Follow server.Status - if ON, start the server automatically
 	if server.Status == entity.StatusOnline {
 		err = p.StartServer(ctx, server.ID)
 		if err != nil {
 			// If failed to start, clean up and return error
+			p.mu.Lock()
 			delete(p.activeServers, server.ID)
+			p.mu.Unlock()
+			if releaseErr := p.allocator.Release(ctx, server.ID); releaseErr != nil {
+				return fmt.Errorf("failed to start server after creation: %w (release port: %v)", err, releaseErr)
+			}
 			return fmt.Errorf("failed to start server after creation: %w", err)
 		}
 	}
@@ -64,13 +97,20 @@ func (p *PortServerProvider) CreateServer(ctx context.Context, server *entity.Se
 
 // UpdateServer updates an existing server's information
 func (p *PortServerProvider) UpdateServer(ctx context.Context, server *entity.Server) error {
-	serverProcess, exists := p.activeServers[server.ID]
-	if !exists {
-		return fmt.Errorf("server %s not found", server.ID)
+	serverProcess, err := p.lookup(ctx, server.ID)
+	if err != nil {
+		return err
 	}
 
-	// Get current status
+	// Apply the behaviour profile, live if the server is running, and get current status
+	serverProcess.mu.Lock()
+	serverProcess.Profile = server.Profile
+	if serverProcess.simulation != nil {
+		serverProcess.simulation.setProfile(server.Profile)
+	}
 	currentStatus := serverProcess.Status
+	listening := serverProcess.HTTPServer != nil
+	serverProcess.mu.Unlock()
 
 	// Follow server.Status - handle status changes
 	if server.Status == entity.StatusOnline && currentStatus == entity.StatusOffline {
@@ -79,7 +119,7 @@ func (p *PortServerProvider) UpdateServer(ctx context.Context, server *entity.Se
 		if err != nil {
 			return fmt.Errorf("failed to start server during update: %w", err)
 		}
-	} else if server.Status == entity.StatusOffline && currentStatus == entity.StatusOnline {
+	} else if server.Status == entity.StatusOffline && (currentStatus == entity.StatusOnline || listening) {
 		// Need to stop the server
 		err := p.StopServer(ctx, server.ID)
 		if err != nil {
@@ -93,85 +133,46 @@ func (p *PortServerProvider) UpdateServer(ctx context.Context, server *entity.Se
 
 // DeleteServer stops and removes a server
 func (p *PortServerProvider) DeleteServer(ctx context.Context, serverID string) error {
-	serverProcess, exists := p.activeServers[serverID]
-	if !exists {
-		return fmt.Errorf("server %s not found", serverID)
+	serverProcess, err := p.lookup(ctx, serverID)
+	if err != nil {
+		return err
 	}
 
 	// Stop the server if it's running
-	if serverProcess.Status == entity.StatusOnline && serverProcess.HTTPServer != nil {
-		err := p.stopServerProcess(serverProcess)
-		if err != nil {
-			return fmt.Errorf("Failed to stop server %s: %v", serverID, err)
-		}
+	err = p.stopServerProcess(serverProcess)
+	if err != nil {
+		return fmt.Errorf("Failed to stop server %s: %v", serverID, err)
 	}
 
-	// Remove from active servers
+	// Remove from active servers and free its port
+	p.mu.Lock()
 	delete(p.activeServers, serverID)
+	p.mu.Unlock()
+
+	if err := p.allocator.Release(ctx, serverID); err != nil {
+		return fmt.Errorf("failed to release port of server %s: %w", serverID, err)
+	}
 
 	return nil
 }
 
 // StartServer starts a server on its designated port
 func (p *PortServerProvider) StartServer(ctx context.Context, serverID string) error {
-	serverProcess, exists := p.activeServers[serverID]
-	if !exists {
-		return fmt.Errorf("server %s not found", serverID)
+	serverProcess, err := p.lookup(ctx, serverID)
+	if err != nil {
+		return err
 	}
 
-	if serverProcess.Status == entity.StatusOnline {
-		return nil // Server is already running
+	started, err := p.startServerProcess(serverProcess)
+	if err != nil || !started {
+		return err
 	}
 
-	// Check if port is available (it should be since we assigned it automatically)
-	if p.isPortInUse(serverProcess.Port) {
-		// If port is now in use, try to find a new one
-		newPort, err := p.findAvailablePort()
-		if err != nil {
-			return fmt.Errorf("port %d is in use and no alternative port found: %w", serverProcess.Port, err)
+	// Crash faults also hit servers started after the fault was injected
+	if p.faults != nil {
+		for _, fault := range p.faults.matching(serverID, server.FaultCrash) {
+			p.crashAfter(serverID, fault)
 		}
-		serverProcess.Port = newPort
-	}
-
-	// Mark status as online before starting
-	serverProcess.Status = entity.StatusOnline
-
-	// Start a simple HTTP server on the port using Golang (simulation)
-	// Create a simple HTTP server that serves on the specified port
-	go func() {
-		mux := http.NewServeMux()
-		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			fmt.Fprintf(w, "Server %s running on localhost:%d",
-				serverID, serverProcess.Port)
-		})
-		mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-			fmt.Fprintf(w, `{"server_id": "%s", "port": %d, "status": "%s"}`,
-				serverID, serverProcess.Port, serverProcess.Status)
-		})
-
-		server := &http.Server{
-			Addr:    fmt.Sprintf("localhost:%d", serverProcess.Port),
-			Handler: mux,
-		}
-
-		// Store server instance for graceful shutdown
-		serverProcess.HTTPServer = server
-
-		// Start server (this will block)
-		err := server.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			serverProcess.Status = entity.StatusOffline
-			serverProcess.HTTPServer = nil
-		}
-	}()
-
-	// Give the server a moment to start
-	time.Sleep(100 * time.Millisecond)
-
-	// Verify server is actually running
-	if !p.isPortInUse(serverProcess.Port) {
-		serverProcess.Status = entity.StatusOffline
-		return fmt.Errorf("failed to start server on localhost:%d", serverProcess.Port)
 	}
 
 	return nil
@@ -179,16 +180,12 @@ func (p *PortServerProvider) StartServer(ctx context.Context, serverID string) e
 
 // StopServer stops a running server
 func (p *PortServerProvider) StopServer(ctx context.Context, serverID string) error {
-	serverProcess, exists := p.activeServers[serverID]
-	if !exists {
-		return fmt.Errorf("server %s not found", serverID)
-	}
-
-	if serverProcess.Status == entity.StatusOffline {
-		return nil // Server is already stopped
+	serverProcess, err := p.lookup(ctx, serverID)
+	if err != nil {
+		return err
 	}
 
-	err := p.stopServerProcess(serverProcess)
+	err = p.stopServerProcess(serverProcess)
 	if err != nil {
 		return fmt.Errorf("failed to stop server %s: %w", serverID, err)
 	}
@@ -198,52 +195,168 @@ func (p *PortServerProvider) StopServer(ctx context.Context, serverID string) er
 
 // GetServerStatus returns the current status of a server by making HTTP health check
 func (p *PortServerProvider) GetServerStatus(ctx context.Context, serverID string) (entity.ServerStatus, error) {
-	serverProcess, exists := p.activeServers[serverID]
-	if !exists {
-		return entity.StatusOffline, fmt.Errorf("server %s not found", serverID)
+	serverProcess, err := p.lookup(ctx, serverID)
+	if err != nil {
+		return entity.StatusOffline, err
 	}
 
+	// The lock is held during the check, so that a concurrent start or stop is not overwritten
+	serverProcess.mu.Lock()
+	defer serverProcess.mu.Unlock()
+
 	// Make HTTP request to server's /status endpoint to check if it's really alive
 	status := p.checkServerHealth(serverProcess)
 
 	// Update the stored status
 	serverProcess.Status = status
 
-	// If server is not responding, clean up HTTPServer reference
-	if status == entity.StatusOffline && serverProcess.HTTPServer != nil {
+	// If server is not responding and its listener is gone, clean up HTTPServer reference.
+	// A listener that is still bound (e.g. failing because of an injected fault) is kept so it can be stopped.
+	if status == entity.StatusOffline && serverProcess.HTTPServer != nil && !isPortInUse(serverProcess.Port) {
 		serverProcess.HTTPServer = nil
 	}
 
 	return status, nil
 }
 
-// Helper methods
-func (p *PortServerProvider) findAvailablePort() (int, error) {
-	// Try to let OS assign an available port by listening on port 0
-	// This is much faster than looping through ports
-	listener, err := net.Listen("tcp", "localhost:0")
+// StartedAt returns when the simulated server started listening, or the zero time when it is stopped
+func (p *PortServerProvider) StartedAt(ctx context.Context, serverID string) (time.Time, error) {
+	serverProcess, err := p.lookup(ctx, serverID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to find available port: %w", err)
+		return time.Time{}, err
+	}
+
+	serverProcess.mu.Lock()
+	defer serverProcess.mu.Unlock()
+
+	if serverProcess.Status != entity.StatusOnline || serverProcess.HTTPServer == nil || serverProcess.simulation == nil {
+		return time.Time{}, nil
+	}
+	return serverProcess.simulation.startedAt, nil
+}
+
+// Shutdown stops every running server. The simulated servers live inside this
+// process, so they are stopped regardless of stopServers.
+func (p *PortServerProvider) Shutdown(ctx context.Context, stopServers bool) error {
+	p.mu.RLock()
+	running := make([]*ServerProcess, 0, len(p.activeServers))
+	for _, serverProcess := range p.activeServers {
+		running = append(running, serverProcess)
+	}
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, serverProcess := range running {
+		wg.Add(1)
+		go func(serverProcess *ServerProcess) {
+			defer wg.Done()
+			_ = p.stopServerProcess(serverProcess)
+		}(serverProcess)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to stop %d servers in time: %w", len(running), ctx.Err())
+	}
+}
+
+// Helper methods
+
+// scheduleCrash schedules a crash of every running server targeted by fault
+func (p *PortServerProvider) scheduleCrash(fault server.Fault) {
+	p.mu.RLock()
+	serverIDs := make([]string, 0, len(p.activeServers))
+	for serverID := range p.activeServers {
+		if targets(fault, serverID) {
+			serverIDs = append(serverIDs, serverID)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, serverID := range serverIDs {
+		p.crashAfter(serverID, fault)
 	}
-	defer listener.Close()
+}
+
+// crashAfter abruptly closes the server's listener after fault.After unless
+// the fault has been cleared in the meantime
+func (p *PortServerProvider) crashAfter(serverID string, fault server.Fault) {
+	time.AfterFunc(fault.After, func() {
+		if !p.faults.active(fault.ID) {
+			return
+		}
 
-	// Get the assigned port
-	addr := listener.Addr().(*net.TCPAddr)
-	port := addr.Port
+		p.mu.RLock()
+		serverProcess, exists := p.activeServers[serverID]
+		p.mu.RUnlock()
+		if !exists {
+			return
+		}
 
-	// Ensure port is after 8000 (main program port)
-	if port <= 8000 {
-		// If OS assigned port <= 8000, try to find a port manually starting from 8001
-		return p.findPortStartingFrom(8001)
+		serverProcess.mu.Lock()
+		defer serverProcess.mu.Unlock()
+		if serverProcess.HTTPServer == nil {
+			return
+		}
+
+		// Close without draining, like a process that died
+		serverProcess.HTTPServer.Close()
+		serverProcess.HTTPServer = nil
+		serverProcess.simulation = nil
+		serverProcess.Status = entity.StatusOffline
+	})
+}
+
+// lookup returns the managed server, restoring it from its persisted port assignment and
+// its saved simulation profile when the provider has restarted since the server was created
+func (p *PortServerProvider) lookup(ctx context.Context, serverID string) (*ServerProcess, error) {
+	p.mu.RLock()
+	serverProcess, exists := p.activeServers[serverID]
+	p.mu.RUnlock()
+	if exists {
+		return serverProcess, nil
 	}
 
-	// Double-check that the port is not already assigned to another server
-	if p.isPortAssignedToServer(port) {
-		// If already assigned, try to find another one
-		return p.findPortStartingFrom(8001)
+	port, assigned, err := p.allocator.Lookup(ctx, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up port of server %s: %w", serverID, err)
+	}
+	if !assigned {
+		return nil, fmt.Errorf("server %s not found", serverID)
+	}
+	saved, err := p.servers.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up profile of server %s: %w", serverID, err)
+	}
+	var profile *entity.SimulationProfile
+	if saved != nil {
+		profile = saved.Profile
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if serverProcess, exists := p.activeServers[serverID]; exists {
+		return serverProcess, nil
+	}
+	serverProcess = &ServerProcess{
+		ServerID: serverID,
+		Host:     "localhost",
+		Port:     port,
+		Status:   entity.StatusOffline,
+		Profile:  profile,
 	}
+	p.activeServers[serverID] = serverProcess
 
-	return port, nil
+	return serverProcess, nil
 }
 
 // checkServerHealth makes HTTP request to server to check if it's healthy
@@ -259,7 +372,7 @@ func (p *PortServerProvider) checkServerHealth(serverProcess *ServerProcess) ent
 	resp, err := client.Get(url)
 	if err != nil {
 		// Fallback: check if port is still in use
-		if p.isPortInUse(serverProcess.Port) {
+		if isPortInUse(serverProcess.Port) {
 			// Port is in use but server not responding properly
 			return entity.StatusOffline
 		}
@@ -275,35 +388,8 @@ func (p *PortServerProvider) checkServerHealth(serverProcess *ServerProcess) ent
 	return entity.StatusOffline
 }
 
-// findPortStartingFrom finds available port starting from specified port (fallback method)
-func (p *PortServerProvider) findPortStartingFrom(startPort int) (int, error) {
-	// Only check a reasonable range to avoid infinite loops
-	maxAttempts := 100
-	for i := 0; i < maxAttempts; i++ {
-		port := startPort + i
-		if port > 65535 {
-			break
-		}
-
-		if !p.isPortInUse(port) && !p.isPortAssignedToServer(port) {
-			return port, nil
-		}
-	}
-	return 0, fmt.Errorf("no available ports found after %d attempts starting from port %d", maxAttempts, startPort)
-}
-
-// isPortAssignedToServer checks if a port is already assigned to any managed server
-func (p *PortServerProvider) isPortAssignedToServer(port int) bool {
-	for _, serverProcess := range p.activeServers {
-		if serverProcess.Port == port {
-			return true
-		}
-	}
-	return false
-}
-
 // isPortInUse checks if a port is currently in use on localhost
-func (p *PortServerProvider) isPortInUse(port int) bool {
+func isPortInUse(port int) bool {
 	conn, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
 		return true // Port is in use
@@ -312,8 +398,64 @@ func (p *PortServerProvider) isPortInUse(port int) bool {
 	return false // Port is available
 }
 
+// startServerProcess starts the simulated HTTP server of a server process.
+// started is false when it was already running.
+func (p *PortServerProvider) startServerProcess(serverProcess *ServerProcess) (started bool, err error) {
+	serverProcess.mu.Lock()
+	defer serverProcess.mu.Unlock()
+
+	if serverProcess.Status == entity.StatusOnline {
+		return false, nil // Server is already running
+	}
+
+	// Ports are stable, so a port taken by something else is an error rather than a reason to move
+	if isPortInUse(serverProcess.Port) {
+		return false, fmt.Errorf("port %d assigned to server %s is in use by another process", serverProcess.Port, serverProcess.ServerID)
+	}
+
+	// Listening before returning means the server accepts connections once started
+	address := fmt.Sprintf("localhost:%d", serverProcess.Port)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return false, fmt.Errorf("failed to start server on %s: %w", address, err)
+	}
+
+	// Serve a simulated HTTP server on the port that answers according to the server's profile
+	simulation := newSimulatedServer(serverProcess.ServerID, serverProcess.Port, serverProcess.Profile)
+	var handler http.Handler = simulation
+	if p.faults != nil {
+		handler = p.faults.middleware(serverProcess.ServerID, simulation)
+	}
+	httpServer := &http.Server{
+		Addr:    address,
+		Handler: handler,
+	}
+
+	serverProcess.HTTPServer = httpServer
+	serverProcess.simulation = simulation
+	serverProcess.Status = entity.StatusOnline
+
+	go func() {
+		err := httpServer.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			serverProcess.mu.Lock()
+			if serverProcess.HTTPServer == httpServer {
+				serverProcess.Status = entity.StatusOffline
+				serverProcess.HTTPServer = nil
+				serverProcess.simulation = nil
+			}
+			serverProcess.mu.Unlock()
+		}
+	}()
+
+	return true, nil
+}
+
 // stopServerProcess stops a server process
 func (p *PortServerProvider) stopServerProcess(serverProcess *ServerProcess) error {
+	serverProcess.mu.Lock()
+	defer serverProcess.mu.Unlock()
+
 	// Stop HTTP server if it exists
 	if serverProcess.HTTPServer != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -327,6 +469,7 @@ func (p *PortServerProvider) stopServerProcess(serverProcess *ServerProcess) err
 		serverProcess.HTTPServer = nil
 	}
 
+	serverProcess.simulation = nil
 	serverProcess.Status = entity.StatusOffline
 	return nil
 }
@@ -0,0 +1,393 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// ProcessStore persists the processes of running servers, so that processes left running by
+// the detach shutdown policy are adopted again when the application restarts
+type ProcessStore interface {
+	// GetProcess returns the process recorded for a server, a zero pid when there is none
+	GetProcess(ctx context.Context, serverID string) (pid int, startedAt time.Time, err error)
+	// SaveProcess records the process of a server, a zero pid records that it is stopped
+	SaveProcess(ctx context.Context, serverID string, pid int, startedAt time.Time) error
+}
+
+// processPollInterval is how often an adopted process, which cannot be waited for, is checked for exit
+const processPollInterval = 500 * time.Millisecond
+
+// ProcessServerProvider implements ServerProvider interface
+// This provider manages servers by running a configured command as a local OS process
+type ProcessServerProvider struct {
+	command   []string
+	allocator *PortAllocator
+	store     ProcessStore
+
+	mu        sync.Mutex
+	processes map[string]*OSProcess
+}
+
+// OSProcess represents a server backed by a local OS process
+type OSProcess struct {
+	ServerID  string
+	Port      int
+	Cmd       *exec.Cmd // nil for a process adopted after a restart
+	PID       int       // ID of the running process and of its process group, 0 when stopped
+	Status    entity.ServerStatus
+	StartedAt time.Time // when the running process was started
+	exited    chan struct{}
+}
+
+// NewProcessServerProvider creates a new instance of ProcessServerProvider.
+// "{port}" and "{id}" in command are replaced with the server's port and ID.
+func NewProcessServerProvider(command []string, allocator *PortAllocator, store ProcessStore) *ProcessServerProvider {
+	return &ProcessServerProvider{
+		command:   command,
+		allocator: allocator,
+		store:     store,
+		processes: make(map[string]*OSProcess),
+	}
+}
+
+// CreateServer registers a server and starts its process if the server is ON
+func (p *ProcessServerProvider) CreateServer(ctx context.Context, server *entity.Server) error {
+	if len(p.command) == 0 {
+		return fmt.Errorf("process provider has no command configured")
+	}
+
+	port, err := p.allocator.Allocate(ctx, server.ID)
+	if err != nil {
+		return fmt.Errorf("failed to allocate port: %w", err)
+	}
+
+	p.mu.Lock()
+	p.processes[server.ID] = &OSProcess{
+		ServerID: server.ID,
+		Port:     port,
+		Status:   entity.StatusOffline,
+	}
+	p.mu.Unlock()
+
+	if server.Status == entity.StatusOnline {
+		if err := p.StartServer(ctx, server.ID); err != nil {
+			p.mu.Lock()
+			delete(p.processes, server.ID)
+			p.mu.Unlock()
+			_ = p.allocator.Release(ctx, server.ID)
+			return fmt.Errorf("failed to start server after creation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateServer starts or stops the process to follow server.Status
+func (p *ProcessServerProvider) UpdateServer(ctx context.Context, server *entity.Server) error {
+	process, err := p.get(ctx, server.ID)
+	if err != nil {
+		return err
+	}
+
+	if server.Status == entity.StatusOnline && process.Status == entity.StatusOffline {
+		if err := p.StartServer(ctx, server.ID); err != nil {
+			return fmt.Errorf("failed to start server during update: %w", err)
+		}
+	} else if server.Status == entity.StatusOffline && process.Status == entity.StatusOnline {
+		if err := p.StopServer(ctx, server.ID); err != nil {
+			return fmt.Errorf("failed to stop server during update: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteServer stops the process and forgets the server
+func (p *ProcessServerProvider) DeleteServer(ctx context.Context, serverID string) error {
+	if err := p.StopServer(ctx, serverID); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	delete(p.processes, serverID)
+	p.mu.Unlock()
+
+	if err := p.allocator.Release(ctx, serverID); err != nil {
+		return fmt.Errorf("failed to release port of server %s: %w", serverID, err)
+	}
+
+	return nil
+}
+
+// StartServer launches the configured command for the server
+func (p *ProcessServerProvider) StartServer(ctx context.Context, serverID string) error {
+	process, err := p.get(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if process.Status == entity.StatusOnline {
+		return nil // Process is already running
+	}
+
+	if isPortInUse(process.Port) {
+		return fmt.Errorf("port %d assigned to server %s is in use by another process", process.Port, serverID)
+	}
+
+	args := make([]string, len(p.command))
+	for i, arg := range p.command {
+		arg = strings.ReplaceAll(arg, "{port}", strconv.Itoa(process.Port))
+		arg = strings.ReplaceAll(arg, "{id}", serverID)
+		args[i] = arg
+	}
+
+	// The process must outlive the request context, so it is not bound to ctx
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"SERVER_ID="+serverID,
+		"SERVER_PORT="+strconv.Itoa(process.Port),
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start process for server %s: %w", serverID, err)
+	}
+	startedAt := time.Now()
+
+	// Without a record, a detached process could not be stopped after a restart
+	if err := p.store.SaveProcess(ctx, serverID, cmd.Process.Pid, startedAt); err != nil {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to record process of server %s: %w", serverID, err)
+	}
+
+	process.Cmd = cmd
+	process.PID = cmd.Process.Pid
+	process.Status = entity.StatusOnline
+	process.StartedAt = startedAt
+	process.exited = p.watch(process, func() { _ = cmd.Wait() })
+
+	return nil
+}
+
+// StopServer terminates the server's process, killing it if it does not exit in time
+func (p *ProcessServerProvider) StopServer(ctx context.Context, serverID string) error {
+	process, err := p.get(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	pid, exited := process.PID, process.exited
+	process.Cmd = nil
+	process.PID = 0
+	process.Status = entity.StatusOffline
+	p.mu.Unlock()
+
+	if pid == 0 {
+		return nil // Process is already stopped
+	}
+
+	// Signal the whole process group so children of the command stop too
+	_ = syscall.Kill(-pid, syscall.SIGTERM)
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		_ = syscall.Kill(-pid, syscall.SIGKILL)
+		<-exited
+	}
+
+	return nil
+}
+
+// GetServerStatus reports ON when the process is alive and accepting connections on its port
+func (p *ProcessServerProvider) GetServerStatus(ctx context.Context, serverID string) (entity.ServerStatus, error) {
+	process, err := p.get(ctx, serverID)
+	if err != nil {
+		return entity.StatusOffline, err
+	}
+
+	p.mu.Lock()
+	running := process.PID != 0
+	port := process.Port
+	p.mu.Unlock()
+
+	if !running {
+		return entity.StatusOffline, nil
+	}
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", port), 3*time.Second)
+	if err != nil {
+		return entity.StatusOffline, nil
+	}
+	conn.Close()
+
+	return entity.StatusOnline, nil
+}
+
+// StartedAt returns when the server's process was started, or the zero time when it is not running
+func (p *ProcessServerProvider) StartedAt(ctx context.Context, serverID string) (time.Time, error) {
+	process, err := p.get(ctx, serverID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if process.Status != entity.StatusOnline || process.PID == 0 {
+		return time.Time{}, nil
+	}
+	return process.StartedAt, nil
+}
+
+// Shutdown stops every running process, or leaves them running when stopServers is false.
+// Processes run in their own process group, so detached ones survive the application,
+// and their recorded process IDs let them be adopted again after a restart.
+func (p *ProcessServerProvider) Shutdown(ctx context.Context, stopServers bool) error {
+	p.mu.Lock()
+	running := make([]string, 0, len(p.processes))
+	for serverID, process := range p.processes {
+		if process.PID != 0 {
+			running = append(running, serverID)
+		}
+	}
+	p.mu.Unlock()
+
+	if !stopServers {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, serverID := range running {
+		wg.Add(1)
+		go func(serverID string) {
+			defer wg.Done()
+			_ = p.StopServer(ctx, serverID)
+		}(serverID)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to stop %d processes in time: %w", len(running), ctx.Err())
+	}
+}
+
+// Helper methods
+
+// get returns the managed process, restoring it from its persisted port assignment when
+// the provider has restarted since the server was created. A process recorded as running
+// is adopted when it still runs and listens on the server's port.
+func (p *ProcessServerProvider) get(ctx context.Context, serverID string) (*OSProcess, error) {
+	p.mu.Lock()
+	process, exists := p.processes[serverID]
+	p.mu.Unlock()
+	if exists {
+		return process, nil
+	}
+
+	port, assigned, err := p.allocator.Lookup(ctx, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up port of server %s: %w", serverID, err)
+	}
+	if !assigned {
+		return nil, fmt.Errorf("server %s not found", serverID)
+	}
+	pid, startedAt, err := p.store.GetProcess(ctx, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up process of server %s: %w", serverID, err)
+	}
+	if pid != 0 && !(isProcessGroupLeader(pid) && isPortInUse(port)) {
+		// The process exited while the application was down, or its ID was reused
+		if err := p.store.SaveProcess(ctx, serverID, 0, time.Time{}); err != nil {
+			return nil, err
+		}
+		pid = 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if process, exists := p.processes[serverID]; exists {
+		return process, nil
+	}
+	process = &OSProcess{
+		ServerID: serverID,
+		Port:     port,
+		Status:   entity.StatusOffline,
+	}
+	if pid != 0 {
+		process.PID = pid
+		process.Status = entity.StatusOnline
+		process.StartedAt = startedAt
+		process.exited = p.watch(process, func() { waitForExit(pid) })
+	}
+	p.processes[serverID] = process
+
+	return process, nil
+}
+
+// watch marks process stopped once wait returns, unless it was restarted in the meantime.
+// The returned channel is closed after that; it must be stored in process.exited before p.mu is released.
+func (p *ProcessServerProvider) watch(process *OSProcess, wait func()) chan struct{} {
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		wait()
+
+		p.mu.Lock()
+		current := process.exited == exited
+		if current {
+			process.Cmd = nil
+			process.PID = 0
+			process.Status = entity.StatusOffline
+		}
+		p.mu.Unlock()
+
+		if current {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = p.store.SaveProcess(ctx, process.ServerID, 0, time.Time{})
+		}
+	}()
+	return exited
+}
+
+// isProcessGroupLeader reports whether pid is alive and leads its process group, as the
+// processes started for servers do
+func isProcessGroupLeader(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	pgid, err := syscall.Getpgid(pid)
+	return err == nil && pgid == pid
+}
+
+// waitForExit blocks until pid has exited. It polls, since only the parent can wait for a process.
+func waitForExit(pid int) {
+	for syscall.Kill(pid, 0) == nil {
+		time.Sleep(processPollInterval)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// simulatedServer is the HTTP handler of a server run by PortServerProvider.
+// Its profile can be swapped while the server is running.
+type simulatedServer struct {
+	serverID  string
+	port      int
+	startedAt time.Time
+
+	mu      sync.RWMutex
+	profile entity.SimulationProfile
+	payload []byte
+}
+
+func newSimulatedServer(serverID string, port int, profile *entity.SimulationProfile) *simulatedServer {
+	s := &simulatedServer{
+		serverID:  serverID,
+		port:      port,
+		startedAt: time.Now(),
+	}
+	s.setProfile(profile)
+	return s
+}
+
+// setProfile replaces the behaviour profile, nil restores the default behaviour
+func (s *simulatedServer) setProfile(profile *entity.SimulationProfile) {
+	var p entity.SimulationProfile
+	if profile != nil {
+		p = *profile
+	}
+
+	payload := []byte(fmt.Sprintf("Server %s running on localhost:%d", s.serverID, s.port))
+	if p.ResponseSize > 0 {
+		payload = bytes.Repeat([]byte("x"), p.ResponseSize)
+	}
+
+	s.mu.Lock()
+	s.profile = p
+	s.payload = payload
+	s.mu.Unlock()
+}
+
+type simulatedStatus struct {
+	ServerID      string              `json:"server_id"`
+	Port          int                 `json:"port"`
+	Status        entity.ServerStatus `json:"status"`
+	StartedAt     time.Time           `json:"started_at"`
+	UptimeSeconds float64             `json:"uptime_seconds"`
+}
+
+func (s *simulatedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	profile, payload := s.profile, s.payload
+	s.mu.RUnlock()
+
+	if delay := sampleLatency(profile.Latency); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if profile.ErrorRate > 0 && rand.Float64() < profile.ErrorRate {
+		http.Error(w, `{"error": "simulated failure"}`, http.StatusInternalServerError)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/status":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(simulatedStatus{
+			ServerID:      s.serverID,
+			Port:          s.port,
+			Status:        entity.StatusOnline,
+			StartedAt:     s.startedAt,
+			UptimeSeconds: time.Since(s.startedAt).Seconds(),
+		})
+		return
+	case "/":
+		w.Write(payload)
+		return
+	}
+
+	for _, endpoint := range profile.Endpoints {
+		if endpoint.Path != r.URL.Path {
+			continue
+		}
+		if endpoint.ContentType != "" {
+			w.Header().Set("Content-Type", endpoint.ContentType)
+		}
+		if endpoint.StatusCode != 0 {
+			w.WriteHeader(endpoint.StatusCode)
+		}
+		w.Write([]byte(endpoint.Body))
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// sampleLatency draws a response delay from the latency distribution
+func sampleLatency(latency entity.LatencyProfile) time.Duration {
+	var ms float64
+	switch latency.Distribution {
+	case entity.LatencyUniform:
+		ms = float64(latency.MinMs) + rand.Float64()*float64(latency.MaxMs-latency.MinMs)
+	case entity.LatencyNormal:
+		ms = float64(latency.MeanMs) + rand.NormFloat64()*float64(latency.StdDevMs)
+	case entity.LatencyExponential:
+		ms = rand.ExpFloat64() * float64(latency.MeanMs)
+	default:
+		ms = float64(latency.MeanMs)
+	}
+
+	if latency.Distribution != entity.LatencyUniform {
+		if latency.MinMs > 0 {
+			ms = math.Max(ms, float64(latency.MinMs))
+		}
+		if latency.MaxMs > 0 {
+			ms = math.Min(ms, float64(latency.MaxMs))
+		}
+	}
+	if ms <= 0 {
+		return 0
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
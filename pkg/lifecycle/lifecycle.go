@@ -0,0 +1,139 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lits-06/vcs-sms/pkg/logger"
+)
+
+// Hook is a named step run during shutdown
+type Hook struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// Lifecycle runs the application until SIGINT/SIGTERM and then executes
+// shutdown hooks in the order they were registered
+type Lifecycle struct {
+	logger  logger.Logger
+	timeout time.Duration
+	hooks   []Hook
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// New creates a lifecycle whose shutdown must finish within timeout
+func New(logger logger.Logger, timeout time.Duration) *Lifecycle {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Lifecycle{
+		logger:  logger.With("component", "lifecycle"),
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Context is cancelled when background workers are asked to stop
+func (l *Lifecycle) Context() context.Context {
+	return l.ctx
+}
+
+// Go runs a background worker. The worker must return once ctx is cancelled.
+func (l *Lifecycle) Go(name string, fn func(ctx context.Context)) {
+	l.workers.Add(1)
+	go func() {
+		defer l.workers.Done()
+		fn(l.ctx)
+		l.logger.Info("Background worker stopped", "worker", name)
+	}()
+}
+
+// OnShutdown registers a hook to run during shutdown
+func (l *Lifecycle) OnShutdown(name string, fn func(ctx context.Context) error) {
+	l.hooks = append(l.hooks, Hook{Name: name, Fn: fn})
+}
+
+// StopWorkers cancels the workers' context and waits for them to return.
+// It is meant to be registered as a shutdown hook.
+func (l *Lifecycle) StopWorkers(ctx context.Context) error {
+	l.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background workers did not stop in time: %w", ctx.Err())
+	}
+}
+
+// Run starts serve and blocks until it fails or a termination signal arrives,
+// then runs the shutdown hooks
+func (l *Lifecycle) Run(serve func() error) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+
+	var runErr error
+	select {
+	case sig := <-signals:
+		l.logger.Info("Shutdown signal received", "signal", sig.String())
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			runErr = err
+			l.logger.Error("Server stopped unexpectedly", "error", err)
+		}
+	}
+
+	if err := l.Shutdown(); err != nil {
+		return errors.Join(runErr, err)
+	}
+	return runErr
+}
+
+// Shutdown runs every hook in registration order within the shutdown timeout.
+// A failing hook does not prevent the following ones from running.
+func (l *Lifecycle) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	var errs []error
+	for _, hook := range l.hooks {
+		start := time.Now()
+		if err := hook.Fn(ctx); err != nil {
+			l.logger.Error("Shutdown step failed", "step", hook.Name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+			continue
+		}
+		l.logger.Info("Shutdown step completed", "step", hook.Name, "duration", time.Since(start))
+	}
+
+	// Make sure workers never outlive the lifecycle even if no hook stopped them
+	l.cancel()
+
+	return errors.Join(errs...)
+}
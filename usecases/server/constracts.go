@@ -2,7 +2,8 @@ package server
 
 import (
 	"context"
-	"mime/multipart"
+	"io"
+	"time"
 
 	"github.com/lits-06/vcs-sms/entity"
 )
@@ -10,18 +11,38 @@ import (
 type UseCase interface {
 	CreateServer(ctx context.Context, req CreateServerRequest) (*entity.Server, error)
 	ViewServer(ctx context.Context, req QueryServerRequest) (*QueryServerResponse, error)
+	SearchServers(ctx context.Context, req SearchServerRequest) (*SearchServerResponse, error)
+	ServerStats(ctx context.Context, req ServerStatsRequest) (*ServerStats, error)
 	UpdateServer(ctx context.Context, req UpdateServerRequest) error
 	DeleteServer(ctx context.Context, serverID string) error
 
-	ImportServersFromExcel(ctx context.Context, file multipart.File) (*ImportRespose, error)
-	ExportServersToExcel(ctx context.Context, req QueryServerRequest) error
+	ImportServers(ctx context.Context, file io.Reader, format ImportFormat, opts ImportOptions) (*ImportRespose, error)
+	ImportTemplate(ctx context.Context, w io.Writer) error
+	ExportServers(ctx context.Context, req ExportServerRequest, w io.Writer) error
 }
 
 // ServerFilter represents filtering criteria for servers
 type ServerFilter struct {
-	Name   string              `json:"name,omitempty" validate:"omitempty" form:"name"`
-	Status entity.ServerStatus `json:"status,omitempty" validate:"omitempty,oneof=ON OFF" form:"status"`
-	IPv4   string              `json:"ipv4,omitempty" validate:"omitempty,ipv4" form:"ipv4"`
+	Name     string              `json:"name,omitempty" validate:"omitempty" form:"name"`
+	Status   entity.ServerStatus `json:"status,omitempty" validate:"omitempty,oneof=ON OFF" form:"status"`
+	IPv4     string              `json:"ipv4,omitempty" validate:"omitempty" form:"ipv4"` // address, CIDR, range or leading octets, see ParseIPFilter
+	IPv6     string              `json:"ipv6,omitempty" form:"ipv6"`                      // comma-separated addresses or CIDR blocks
+	MAC      string              `json:"mac,omitempty" form:"mac"`                        // comma-separated MAC addresses
+	Provider string              `json:"provider,omitempty" validate:"omitempty" form:"provider"`
+
+	Environment string `json:"environment,omitempty" form:"environment"`
+	Location    string `json:"location,omitempty" form:"location"`
+	Owner       string `json:"owner,omitempty" form:"owner"`
+	Labels      string `json:"labels,omitempty" form:"labels"` // label selector, see ParseLabelSelector
+	Group       string `json:"group,omitempty" form:"group"`   // ID or name of a server group
+
+	// Time ranges, each bound an RFC 3339 time, a YYYY-MM-DD date or an offset from now such as -7d, see CreatedRange
+	CreatedFrom string `json:"created_from,omitempty" form:"created_from"`
+	CreatedTo   string `json:"created_to,omitempty" form:"created_to"`
+	UpdatedFrom string `json:"updated_from,omitempty" form:"updated_from"`
+	UpdatedTo   string `json:"updated_to,omitempty" form:"updated_to"`
+
+	Query string `json:"q,omitempty" form:"q"` // query expression ANDed with the other criteria, see ParseQuery
 }
 
 // SortOrder represents sorting direction
@@ -34,8 +55,8 @@ const (
 
 // ServerSort represents sorting criteria
 type ServerSort struct {
-	Sort  string    `json:"sort,omitempty" validate:"omitempty,oneof=name status created_at updated_at" form:"sort"` // name, status, created_at, updated_at
-	Order SortOrder `json:"order,omitempty" validate:"omitempty,oneof=asc desc" form:"order"`                        // asc, desc
+	Sort  string    `json:"sort,omitempty" form:"sort"`                                       // comma-separated fields, - for descending, such as status,-name,ipv4, see ParseSort
+	Order SortOrder `json:"order,omitempty" validate:"omitempty,oneof=asc desc" form:"order"` // direction of fields without a sign, asc by default
 }
 
 // Pagination represents pagination parameters
@@ -45,33 +66,177 @@ type ServerPagination struct {
 }
 
 type CreateServerRequest struct {
-	ID     string              `json:"id" validate:"required"`
-	Name   string              `json:"name" validate:"required"`
-	IPv4   string              `json:"ipv4" validate:"required,ipv4"`
-	Status entity.ServerStatus `json:"status" validate:"omitempty,oneof=ON OFF"`
+	ID       string                    `json:"id" validate:"required"`
+	Name     string                    `json:"name" validate:"required"`
+	IPv4     string                    `json:"ipv4,omitempty" validate:"omitempty,ipv4"`
+	IPv6     string                    `json:"ipv6,omitempty" validate:"omitempty,ipv6"`
+	Status   entity.ServerStatus       `json:"status" validate:"omitempty,oneof=ON OFF"`
+	Provider string                    `json:"provider,omitempty" validate:"omitempty"` // port, process, external; empty uses the default provider
+	Profile  *entity.SimulationProfile `json:"profile,omitempty" validate:"omitempty"`  // behaviour of simulated servers
+
+	Description string        `json:"description,omitempty"`
+	Environment string        `json:"environment,omitempty"`
+	Location    string        `json:"location,omitempty"`
+	Owner       string        `json:"owner,omitempty"`
+	Labels      entity.Labels `json:"labels,omitempty"`
+
+	// Interfaces of the server, the first one is primary unless another is marked.
+	// Without interfaces, IPv4 and IPv6 make up a single eth0 interface; with them,
+	// IPv4 and IPv6 may be omitted and must otherwise match the primary interface.
+	Interfaces entity.NetworkInterfaces `json:"interfaces,omitempty"`
 }
 
 type QueryServerRequest struct {
 	Filter     ServerFilter     `json:"filter"`
 	Pagination ServerPagination `json:"pagination"`
 	Sort       ServerSort       `json:"sort"`
+
+	// Response shaping of the list endpoint, exports choose their columns instead
+	Fields  string `json:"fields,omitempty" form:"fields"`   // comma-separated fields returned for each server, see ParseFields
+	Include string `json:"include,omitempty" form:"include"` // comma-separated optional data: live, uptime
+}
+
+type ExportServerRequest struct {
+	QueryServerRequest
+	Format     ExportFormat `json:"format,omitempty" validate:"omitempty,oneof=xlsx csv json ndjson" form:"format"`
+	Columns    string       `json:"columns,omitempty" form:"columns"`         // comma-separated column keys in output order, such as id,name,status
+	TZ         string       `json:"tz,omitempty" form:"tz"`                   // IANA time zone of timestamps, server local time by default
+	TimeFormat string       `json:"time_format,omitempty" form:"time_format"` // iso for RFC 3339 timestamps in tabular formats
+	Locale     string       `json:"locale,omitempty" form:"locale"`           // language of header labels, en (default) or vi
 }
 
 type QueryServerResponse struct {
 	Servers *[]entity.Server `json:"servers"`
 	Total   int              `json:"total"`
+
+	// Views replace Servers in the response when fields or included data were requested
+	Views []ServerView `json:"-"`
+}
+
+type SearchServerRequest struct {
+	Query string `json:"q" form:"q"`         // words matched against name, ID, addresses and metadata
+	Limit int    `json:"limit" form:"limit"` // number of results, 20 by default and at most 100
+}
+
+type SearchServerResponse struct {
+	Query   string               `json:"query"`
+	Results []ServerSearchResult `json:"results"`
+}
+
+// ServerSearchResult is a server found by a search, best matches have the highest score
+type ServerSearchResult struct {
+	Server     entity.Server     `json:"server"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"` // field -> HTML-escaped value with matches wrapped in <mark>
+}
+
+type ServerStatsRequest struct {
+	Filter ServerFilter `json:"filter"`
+	TZ     string       `json:"tz,omitempty" form:"tz"` // IANA time zone of the histogram days and weeks, UTC by default
+}
+
+// ServerStats aggregates the servers matching a filter
+type ServerStats struct {
+	Total          int                     `json:"total"`
+	ByStatus       map[string]int          `json:"by_status"`
+	ByEnvironment  map[string]int          `json:"by_environment"` // servers without an environment are counted under ""
+	BySubnet       []StatsCount            `json:"by_subnet"`      // /24 subnets of primary IPv4 addresses, largest first
+	ByLabel        map[string][]StatsCount `json:"by_label"`       // label key -> servers per value, largest first
+	CreatedPerDay  []StatsBucket           `json:"created_per_day"`
+	CreatedPerWeek []StatsBucket           `json:"created_per_week"` // weeks start on Monday
+}
+
+// StatsCount is the number of servers sharing a value
+type StatsCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// StatsBucket is the number of servers created in the period starting at Start.
+// Periods without servers are left out.
+type StatsBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
 }
 
 type UpdateServerRequest struct {
-	ID     string              `json:"id"`
-	Name   string              `json:"name,omitempty" validate:"omitempty"`
-	IPv4   string              `json:"ipv4,omitempty" validate:"omitempty,ipv4"`
-	Status entity.ServerStatus `json:"status,omitempty" validate:"omitempty,oneof=ON OFF"`
+	ID      string                    `json:"id"`
+	Name    string                    `json:"name,omitempty" validate:"omitempty"`
+	IPv4    string                    `json:"ipv4,omitempty" validate:"omitempty,ipv4"` // changes the address of the primary interface
+	IPv6    string                    `json:"ipv6,omitempty" validate:"omitempty,ipv6"` // changes the address of the primary interface
+	Status  entity.ServerStatus       `json:"status,omitempty" validate:"omitempty,oneof=ON OFF"`
+	Profile *entity.SimulationProfile `json:"profile,omitempty" validate:"omitempty"` // replaces the current profile when set
+
+	// Metadata is changed when set, an empty string clears it
+	Description *string       `json:"description,omitempty"`
+	Environment *string       `json:"environment,omitempty"`
+	Location    *string       `json:"location,omitempty"`
+	Owner       *string       `json:"owner,omitempty"`
+	Labels      entity.Labels `json:"labels,omitempty"` // replaces the current labels when set, {} removes them all
+
+	Interfaces entity.NetworkInterfaces `json:"interfaces,omitempty"` // replaces every interface when set
+}
+
+type ImportOptions struct {
+	Mode   ImportMode `json:"mode" form:"mode"`       // create-only (default), upsert or replace
+	DryRun bool       `json:"dry_run" form:"dry_run"` // validate every row without creating anything
+	Atomic bool       `json:"atomic" form:"atomic"`   // import every row or none, in one transaction
+	Report bool       `json:"report" form:"report"`   // build an XLSX copy of the file annotated with each row's outcome
 }
 
 type ImportRespose struct {
-	SuccessCount   int
+	Mode           ImportMode
+	DryRun         bool
+	Atomic         bool
+	SuccessCount   int // rows created, updated or unchanged
+	CreatedCount   int
+	UpdatedCount   int
+	UnchangedCount int
 	FailureCount   int
+	SkippedCount   int
+	DeletedCount   int      // replace mode only
 	SuccessServers []string // format: "ID:Name"
 	FailureServers []string // format: "ID:Name - error message"
+	SkippedServers []string // format: "ID:Name - reason"
+	DeletedServers []string // format: "ID:Name"
+
+	// Only filled in dry-run mode
+	WouldCreate []ImportRowReport
+	WouldUpdate []ImportRowReport
+	WouldSkip   []ImportRowReport
+	WouldFail   []ImportRowReport
+	WouldDelete []ImportRowReport
+
+	// Outcome of each row keyed by line, used to annotate the import report
+	Outcomes map[int]ImportRowOutcome `json:"-"`
+	// Annotated XLSX copy of the file, only built when requested
+	Report []byte `json:"-"`
+}
+
+// ImportRowStatus is what happened to an import row
+type ImportRowStatus string
+
+const (
+	ImportRowCreated   ImportRowStatus = "created"
+	ImportRowUpdated   ImportRowStatus = "updated"
+	ImportRowUnchanged ImportRowStatus = "unchanged"
+	ImportRowSkipped   ImportRowStatus = "skipped"
+	ImportRowFailed    ImportRowStatus = "failed"
+)
+
+// ImportRowOutcome is the outcome of one import row
+type ImportRowOutcome struct {
+	Status  ImportRowStatus
+	Message string   // error or skip reason
+	Columns []string // import columns holding invalid values
+}
+
+// ImportRowReport is the outcome of validating one import row
+type ImportRowReport struct {
+	Line    int      `json:"line"`
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Errors  []string `json:"errors,omitempty"`  // every problem found in the row
+	Changes []string `json:"changes,omitempty"` // fields an update changes, format: "field: old -> new"
+	Reason  string   `json:"reason,omitempty"`  // why the row is skipped
 }
@@ -0,0 +1,518 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/xuri/excelize/v2"
+)
+
+// ExportFormat is a file format servers can be exported to
+type ExportFormat string
+
+const (
+	FormatXLSX   ExportFormat = "xlsx"
+	FormatCSV    ExportFormat = "csv"
+	FormatJSON   ExportFormat = "json"
+	FormatNDJSON ExportFormat = "ndjson"
+)
+
+var exportMediaTypes = map[ExportFormat]string{
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatCSV:    "text/csv",
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// ContentType returns the MIME type of the format
+func (f ExportFormat) ContentType() string {
+	return exportMediaTypes[f]
+}
+
+// Extension returns the file extension of the format, without the dot
+func (f ExportFormat) Extension() string {
+	return string(f)
+}
+
+// ParseExportFormat validates a format name, empty defaults to XLSX
+func ParseExportFormat(name string) (ExportFormat, error) {
+	if name == "" {
+		return FormatXLSX, nil
+	}
+	format := ExportFormat(name)
+	if _, ok := exportMediaTypes[format]; !ok {
+		return "", fmt.Errorf("unsupported export format %q", name)
+	}
+	return format, nil
+}
+
+// ExportFormatForMediaType returns the format producing mediaType
+func ExportFormatForMediaType(mediaType string) (ExportFormat, bool) {
+	for format, formatMediaType := range exportMediaTypes {
+		if formatMediaType == mediaType {
+			return format, true
+		}
+	}
+	// Common aliases
+	switch mediaType {
+	case "application/ndjson", "application/jsonl", "application/jsonlines":
+		return FormatNDJSON, true
+	case "application/csv":
+		return FormatCSV, true
+	}
+	return "", false
+}
+
+// ErrInvalidExportRequest is returned when export options cannot be applied
+var ErrInvalidExportRequest = errors.New("invalid export request")
+
+// ExportColumn is a server field written by every exporter
+type ExportColumn struct {
+	Key    string
+	Header string
+	Value  func(server entity.Server) interface{}
+}
+
+// exportColumns are the columns that can be exported, in their default order
+var exportColumns = []ExportColumn{
+	{Key: "id", Header: "ID", Value: func(s entity.Server) interface{} { return s.ID }},
+	{Key: "name", Header: "Name", Value: func(s entity.Server) interface{} { return s.Name }},
+	{Key: "ipv4", Header: "IPv4", Value: func(s entity.Server) interface{} { return s.IPv4 }},
+	{Key: "ipv6", Header: "IPv6", Value: func(s entity.Server) interface{} { return s.IPv6 }},
+	{Key: "interfaces", Header: "Interfaces", Value: func(s entity.Server) interface{} { return s.Interfaces }},
+	{Key: "status", Header: "Status", Value: func(s entity.Server) interface{} { return string(s.Status) }},
+	{Key: "provider", Header: "Provider", Value: func(s entity.Server) interface{} { return s.Provider }},
+	{Key: "description", Header: "Description", Value: func(s entity.Server) interface{} { return s.Description }},
+	{Key: "environment", Header: "Environment", Value: func(s entity.Server) interface{} { return s.Environment }},
+	{Key: "location", Header: "Location", Value: func(s entity.Server) interface{} { return s.Location }},
+	{Key: "owner", Header: "Owner", Value: func(s entity.Server) interface{} { return s.Owner }},
+	{Key: "labels", Header: "Labels", Value: func(s entity.Server) interface{} { return s.Labels }},
+	{Key: "created_at", Header: "Created At", Value: func(s entity.Server) interface{} { return s.CreatedAt }},
+	{Key: "updated_at", Header: "Updated At", Value: func(s entity.Server) interface{} { return s.UpdatedAt }},
+}
+
+// defaultExportColumns are exported when no columns are requested
+var defaultExportColumns = []string{
+	"id", "name", "ipv4", "ipv6", "interfaces", "status", "description", "environment", "location", "owner", "labels", "created_at", "updated_at",
+}
+
+// exportHeaders holds the header labels of each supported locale other than English, keyed by column
+var exportHeaders = map[string]map[string]string{
+	"vi": {
+		"id":          "Mã máy chủ",
+		"name":        "Tên máy chủ",
+		"ipv4":        "Địa chỉ IPv4",
+		"ipv6":        "Địa chỉ IPv6",
+		"interfaces":  "Giao diện mạng",
+		"status":      "Trạng thái",
+		"provider":    "Nhà cung cấp",
+		"description": "Mô tả",
+		"environment": "Môi trường",
+		"location":    "Vị trí",
+		"owner":       "Người phụ trách",
+		"labels":      "Nhãn",
+		"created_at":  "Ngày tạo",
+		"updated_at":  "Ngày cập nhật",
+	},
+}
+
+// Export time formats
+const (
+	ExportTimeDefault = ""    // 2006-01-02 15:04:05
+	ExportTimeISO     = "iso" // RFC 3339 with the zone offset
+)
+
+// ExportLayout describes how servers are laid out in an export
+type ExportLayout struct {
+	Columns    []ExportColumn
+	Location   *time.Location // zone timestamps are written in
+	TimeLayout string         // layout of timestamps in tabular formats
+
+	// Time filters of the export, shown in the Excel summary
+	CreatedRange TimeRange
+	UpdatedRange TimeRange
+}
+
+// formatBound formats a time range bound like the timestamps of the export, the zero time as blank
+func (l ExportLayout) formatBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(l.Location).Format(l.TimeLayout)
+}
+
+// NewExportLayout resolves the columns, time zone, time format and locale requested in req.
+// Columns are comma-separated keys, the time zone is an IANA name such as UTC or Asia/Ho_Chi_Minh,
+// and the locale is a language tag such as vi or en-US.
+func NewExportLayout(req ExportServerRequest) (ExportLayout, error) {
+	layout := ExportLayout{Location: time.Local, TimeLayout: "2006-01-02 15:04:05"}
+
+	if req.TZ != "" {
+		location, err := time.LoadLocation(req.TZ)
+		if err != nil {
+			return ExportLayout{}, fmt.Errorf("%w: unknown time zone %q", ErrInvalidExportRequest, req.TZ)
+		}
+		layout.Location = location
+	}
+
+	switch strings.ToLower(req.TimeFormat) {
+	case ExportTimeDefault:
+	case ExportTimeISO:
+		layout.TimeLayout = time.RFC3339
+	default:
+		return ExportLayout{}, fmt.Errorf("%w: unsupported time format %q (use iso)", ErrInvalidExportRequest, req.TimeFormat)
+	}
+
+	now := time.Now()
+	var err error
+	if layout.CreatedRange, err = req.Filter.CreatedRange(now); err != nil {
+		return ExportLayout{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if layout.UpdatedRange, err = req.Filter.UpdatedRange(now); err != nil {
+		return ExportLayout{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+
+	language := strings.ToLower(strings.SplitN(strings.ReplaceAll(req.Locale, "_", "-"), "-", 2)[0])
+	headers, ok := exportHeaders[language]
+	if !ok && language != "" && language != "en" {
+		return ExportLayout{}, fmt.Errorf("%w: unsupported locale %q (use en or vi)", ErrInvalidExportRequest, req.Locale)
+	}
+
+	keys := defaultExportColumns
+	if strings.TrimSpace(req.Columns) != "" {
+		keys = strings.Split(req.Columns, ",")
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		column, ok := exportColumn(key)
+		if !ok {
+			return ExportLayout{}, fmt.Errorf("%w: unknown column %q (use %s)", ErrInvalidExportRequest, key, strings.Join(exportColumnKeys(), ", "))
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if header, ok := headers[key]; ok {
+			column.Header = header
+		}
+		layout.Columns = append(layout.Columns, column)
+	}
+
+	return layout, nil
+}
+
+func exportColumn(key string) (ExportColumn, bool) {
+	for _, column := range exportColumns {
+		if column.Key == key {
+			return column, true
+		}
+	}
+	return ExportColumn{}, false
+}
+
+func exportColumnKeys() []string {
+	keys := make([]string, len(exportColumns))
+	for i, column := range exportColumns {
+		keys[i] = column.Key
+	}
+	return keys
+}
+
+// Headers returns the header labels of the columns
+func (l ExportLayout) Headers() []string {
+	headers := make([]string, len(l.Columns))
+	for i, column := range l.Columns {
+		headers[i] = column.Header
+	}
+	return headers
+}
+
+// value returns the value of column for server, with timestamps in the layout's zone
+func (l ExportLayout) value(column ExportColumn, server entity.Server) interface{} {
+	value := column.Value(server)
+	if t, ok := value.(time.Time); ok && l.Location != nil {
+		return t.In(l.Location)
+	}
+	return value
+}
+
+// cell renders the value of column for server in tabular formats
+func (l ExportLayout) cell(column ExportColumn, server entity.Server) interface{} {
+	switch value := l.value(column, server).(type) {
+	case time.Time:
+		return value.Format(l.TimeLayout)
+	case entity.Labels:
+		return value.String()
+	case entity.NetworkInterfaces:
+		return value.String()
+	default:
+		return value
+	}
+}
+
+// Exporter writes servers to w in a single file format
+type Exporter interface {
+	WriteServer(server entity.Server) error
+	// Close completes the file. Nothing may be written after Close.
+	Close() error
+	// Release frees the resources held for the file without completing it. It may be called after Close.
+	Release()
+}
+
+// NewExporter creates an exporter for format writing servers to w as laid out by layout
+func NewExporter(format ExportFormat, w io.Writer, layout ExportLayout) (Exporter, error) {
+	switch format {
+	case FormatXLSX:
+		return newXLSXExporter(w, layout)
+	case FormatCSV:
+		return newCSVExporter(w, layout)
+	case FormatJSON:
+		return newJSONExporter(w, layout, false), nil
+	case FormatNDJSON:
+		return newJSONExporter(w, layout, true), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// Excel export sheets
+const (
+	exportDataSheet    = "Servers"
+	exportSummarySheet = "Summary"
+)
+
+// xlsxExporter streams rows into a workbook; the workbook is written to w on Close.
+// The data sheet has a frozen header row and an autofilter, and offline servers are highlighted.
+// A summary sheet with status counts, newest and oldest servers and servers per subnet is added on Close.
+type xlsxExporter struct {
+	w       io.Writer
+	layout  ExportLayout
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	row     int
+	summary *exportSummary
+}
+
+func newXLSXExporter(w io.Writer, layout ExportLayout) (*xlsxExporter, error) {
+	f := excelize.NewFile()
+
+	if err := f.SetSheetName("Sheet1", exportDataSheet); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to name data sheet: %w", err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+	})
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create header style: %w", err)
+	}
+
+	// Rows are flushed to a temporary file by the stream writer instead of being kept in memory
+	sw, err := f.NewStreamWriter(exportDataSheet)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create stream writer: %w", err)
+	}
+
+	// Panes and widths must be set before the first row is written
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to freeze header row: %w", err)
+	}
+	if len(layout.Columns) > 0 {
+		if err := sw.SetColWidth(1, len(layout.Columns), 20); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to set column widths: %w", err)
+		}
+	}
+
+	headers := make([]interface{}, len(layout.Columns))
+	for i, header := range layout.Headers() {
+		headers[i] = excelize.Cell{StyleID: headerStyle, Value: header}
+	}
+	if err := sw.SetRow("A1", headers); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write header row: %w", err)
+	}
+
+	return &xlsxExporter{w: w, layout: layout, file: f, stream: sw, row: 2, summary: newExportSummary()}, nil
+}
+
+func (e *xlsxExporter) WriteServer(server entity.Server) error {
+	values := make([]interface{}, len(e.layout.Columns))
+	for i, column := range e.layout.Columns {
+		values[i] = e.layout.cell(column, server)
+	}
+	e.summary.add(server)
+
+	cell, _ := excelize.CoordinatesToCellName(1, e.row)
+	e.row++
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxExporter) Close() error {
+	defer e.Release()
+
+	// Sheet settings are written by Flush, so they are applied first
+	if err := e.formatDataSheet(); err != nil {
+		return err
+	}
+	if err := e.stream.Flush(); err != nil {
+		return fmt.Errorf("failed to flush Excel rows: %w", err)
+	}
+	if err := e.summary.write(e.file, e.layout); err != nil {
+		return err
+	}
+	if err := e.file.Write(e.w); err != nil {
+		return fmt.Errorf("failed to write Excel file: %w", err)
+	}
+	return nil
+}
+
+// Release removes the temporary files of the streamed rows
+func (e *xlsxExporter) Release() {
+	_ = e.file.Close()
+}
+
+// formatDataSheet adds the autofilter and highlights offline servers once the number of rows is known
+func (e *xlsxExporter) formatDataSheet() error {
+	if len(e.layout.Columns) == 0 {
+		return nil
+	}
+
+	lastCell, _ := excelize.CoordinatesToCellName(len(e.layout.Columns), max(e.row-1, 1))
+	if err := e.file.AutoFilter(exportDataSheet, "A1:"+lastCell, nil); err != nil {
+		return fmt.Errorf("failed to add autofilter: %w", err)
+	}
+
+	status := -1
+	for i, column := range e.layout.Columns {
+		if column.Key == "status" {
+			status = i + 1
+		}
+	}
+	if status < 0 || e.row <= 2 {
+		return nil // Status is not exported or there are no rows
+	}
+
+	offStyle, err := e.file.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create offline style: %w", err)
+	}
+	statusColumn, _ := excelize.ColumnNumberToName(status)
+	err = e.file.SetConditionalFormat(exportDataSheet, "A2:"+lastCell, []excelize.ConditionalFormatOptions{{
+		Type:     "formula",
+		Criteria: fmt.Sprintf(`$%s2="%s"`, statusColumn, entity.StatusOffline),
+		Format:   &offStyle,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to highlight offline servers: %w", err)
+	}
+	return nil
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+	layout ExportLayout
+}
+
+func newCSVExporter(w io.Writer, layout ExportLayout) (*csvExporter, error) {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(layout.Headers()); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	return &csvExporter{writer: writer, layout: layout}, nil
+}
+
+func (e *csvExporter) WriteServer(server entity.Server) error {
+	record := make([]string, len(e.layout.Columns))
+	for i, column := range e.layout.Columns {
+		record[i] = fmt.Sprint(e.layout.cell(column, server))
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvExporter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExporter) Release() {}
+
+// jsonExporter writes a JSON array, or one object per line when lines is set.
+// Object keys follow the column order and are not localized; timestamps are always RFC 3339.
+type jsonExporter struct {
+	w      io.Writer
+	layout ExportLayout
+	lines  bool
+	count  int
+}
+
+func newJSONExporter(w io.Writer, layout ExportLayout, lines bool) *jsonExporter {
+	return &jsonExporter{w: w, layout: layout, lines: lines}
+}
+
+func (e *jsonExporter) WriteServer(server entity.Server) error {
+	var buf bytes.Buffer
+	switch {
+	case e.lines:
+	case e.count == 0:
+		buf.WriteString("[\n")
+	default:
+		buf.WriteString(",\n")
+	}
+
+	buf.WriteByte('{')
+	for i, column := range e.layout.Columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(column.Key)
+		value, err := json.Marshal(e.layout.value(column, server))
+		if err != nil {
+			return fmt.Errorf("failed to encode %s of server %s: %w", column.Key, server.ID, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	if e.lines {
+		buf.WriteByte('\n')
+	}
+
+	e.count++
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+func (e *jsonExporter) Close() error {
+	if e.lines {
+		return nil
+	}
+
+	closing := "\n]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+func (e *jsonExporter) Release() {}
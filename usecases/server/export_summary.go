@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportSummaryListSize is the number of newest and oldest servers listed in the summary
+const exportSummaryListSize = 5
+
+// exportSummary collects the figures of the Excel summary sheet while servers are exported
+type exportSummary struct {
+	total    int
+	statuses map[entity.ServerStatus]int
+	newest   []entity.Server // newest first
+	oldest   []entity.Server // oldest first
+	subnets  map[string]int  // keyed by /24 network
+}
+
+func newExportSummary() *exportSummary {
+	return &exportSummary{
+		statuses: make(map[entity.ServerStatus]int),
+		subnets:  make(map[string]int),
+	}
+}
+
+func (s *exportSummary) add(server entity.Server) {
+	s.total++
+	s.statuses[server.Status]++
+
+	s.newest = insertRanked(s.newest, server, func(a, b entity.Server) bool { return a.CreatedAt.After(b.CreatedAt) })
+	s.oldest = insertRanked(s.oldest, server, func(a, b entity.Server) bool { return a.CreatedAt.Before(b.CreatedAt) })
+
+	if ip := net.ParseIP(server.IPv4).To4(); ip != nil {
+		network := net.IPNet{IP: ip.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}
+		s.subnets[network.String()]++
+	}
+}
+
+// insertRanked inserts server into list, which is ordered by before, keeping at most
+// exportSummaryListSize servers
+func insertRanked(list []entity.Server, server entity.Server, before func(a, b entity.Server) bool) []entity.Server {
+	i := sort.Search(len(list), func(i int) bool { return before(server, list[i]) })
+	if i >= exportSummaryListSize {
+		return list
+	}
+	list = append(list, entity.Server{})
+	copy(list[i+1:], list[i:])
+	list[i] = server
+	if len(list) > exportSummaryListSize {
+		list = list[:exportSummaryListSize]
+	}
+	return list
+}
+
+// write adds the summary sheet to f, with a pie chart of servers by status
+func (s *exportSummary) write(f *excelize.File, layout ExportLayout) error {
+	if _, err := f.NewSheet(exportSummarySheet); err != nil {
+		return fmt.Errorf("failed to create summary sheet: %w", err)
+	}
+
+	titleStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 13}})
+	if err != nil {
+		return fmt.Errorf("failed to create title style: %w", err)
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %w", err)
+	}
+
+	row := 1
+	section := func(title string, headers ...string) error {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := f.SetCellValue(exportSummarySheet, cell, title); err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(exportSummarySheet, cell, cell, titleStyle); err != nil {
+			return err
+		}
+		row++
+		return writeSummaryRow(f, &row, headerStyle, stringsToValues(headers)...)
+	}
+
+	// Servers by status
+	if err := section("Servers by status", "Status", "Servers"); err != nil {
+		return fmt.Errorf("failed to write status counts: %w", err)
+	}
+	statusRow := row
+	statuses := []entity.ServerStatus{entity.StatusOnline, entity.StatusOffline}
+	for status := range s.statuses {
+		if status != entity.StatusOnline && status != entity.StatusOffline {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses[2:], func(i, j int) bool { return statuses[2+i] < statuses[2+j] })
+	for _, status := range statuses {
+		label := string(status)
+		if label == "" {
+			label = "(none)"
+		}
+		if err := writeSummaryRow(f, &row, 0, label, s.statuses[status]); err != nil {
+			return fmt.Errorf("failed to write status counts: %w", err)
+		}
+	}
+	if err := writeSummaryRow(f, &row, headerStyle, "Total", s.total); err != nil {
+		return fmt.Errorf("failed to write status counts: %w", err)
+	}
+
+	// Newest and oldest servers
+	for _, list := range []struct {
+		title   string
+		servers []entity.Server
+	}{
+		{"Newest servers", s.newest},
+		{"Oldest servers", s.oldest},
+	} {
+		row++
+		if err := section(list.title, "ID", "Name", "Address", "Status", "Created At"); err != nil {
+			return fmt.Errorf("failed to write %s: %w", list.title, err)
+		}
+		for _, server := range list.servers {
+			createdAt := server.CreatedAt.In(layout.Location).Format(layout.TimeLayout)
+			if err := writeSummaryRow(f, &row, 0, server.ID, server.Name, server.PrimaryAddress(), string(server.Status), createdAt); err != nil {
+				return fmt.Errorf("failed to write %s: %w", list.title, err)
+			}
+		}
+	}
+
+	// Servers per subnet, largest first
+	row++
+	if err := section("Servers per subnet", "Subnet", "Servers"); err != nil {
+		return fmt.Errorf("failed to write subnets: %w", err)
+	}
+	subnets := make([]string, 0, len(s.subnets))
+	for subnet := range s.subnets {
+		subnets = append(subnets, subnet)
+	}
+	sort.Slice(subnets, func(i, j int) bool {
+		if s.subnets[subnets[i]] != s.subnets[subnets[j]] {
+			return s.subnets[subnets[i]] > s.subnets[subnets[j]]
+		}
+		return compareSubnets(subnets[i], subnets[j]) < 0
+	})
+	for _, subnet := range subnets {
+		if err := writeSummaryRow(f, &row, 0, subnet, s.subnets[subnet]); err != nil {
+			return fmt.Errorf("failed to write subnets: %w", err)
+		}
+	}
+
+	// Time ranges the export was filtered by, open bounds left blank
+	if !layout.CreatedRange.IsZero() || !layout.UpdatedRange.IsZero() {
+		row++
+		if err := section("Time range", "Field", "From", "Before"); err != nil {
+			return fmt.Errorf("failed to write time range: %w", err)
+		}
+		for _, r := range []struct {
+			label string
+			TimeRange
+		}{
+			{"Created", layout.CreatedRange},
+			{"Updated", layout.UpdatedRange},
+		} {
+			if r.IsZero() {
+				continue
+			}
+			if err := writeSummaryRow(f, &row, 0, r.label, layout.formatBound(r.From), layout.formatBound(r.To)); err != nil {
+				return fmt.Errorf("failed to write time range: %w", err)
+			}
+		}
+	}
+
+	if err := f.SetColWidth(exportSummarySheet, "A", "A", 22); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(exportSummarySheet, "B", "E", 20); err != nil {
+		return err
+	}
+
+	// Pie chart of the ON and OFF counts
+	err = f.AddChart(exportSummarySheet, "G2", &excelize.Chart{
+		Type: excelize.Pie,
+		Series: []excelize.ChartSeries{{
+			Name:       fmt.Sprintf("'%s'!$A$1", exportSummarySheet),
+			Categories: fmt.Sprintf("'%s'!$A$%d:$A$%d", exportSummarySheet, statusRow, statusRow+1),
+			Values:     fmt.Sprintf("'%s'!$B$%d:$B$%d", exportSummarySheet, statusRow, statusRow+1),
+		}},
+		Title:    []excelize.RichTextRun{{Text: "Servers by status"}},
+		Legend:   excelize.ChartLegend{Position: "right"},
+		PlotArea: excelize.ChartPlotArea{ShowPercent: true, ShowCatName: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add status chart: %w", err)
+	}
+
+	return nil
+}
+
+// writeSummaryRow writes values to the next row of the summary sheet, styled with style when it is not 0
+func writeSummaryRow(f *excelize.File, row *int, style int, values ...interface{}) error {
+	cell, _ := excelize.CoordinatesToCellName(1, *row)
+	if err := f.SetSheetRow(exportSummarySheet, cell, &values); err != nil {
+		return err
+	}
+	if style != 0 {
+		last, _ := excelize.CoordinatesToCellName(len(values), *row)
+		if err := f.SetCellStyle(exportSummarySheet, cell, last, style); err != nil {
+			return err
+		}
+	}
+	*row++
+	return nil
+}
+
+func stringsToValues(values []string) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = value
+	}
+	return converted
+}
+
+// compareSubnets orders networks such as 10.0.2.0/24 numerically
+func compareSubnets(a, b string) int {
+	ipA, _, _ := net.ParseCIDR(a)
+	ipB, _, _ := net.ParseCIDR(b)
+	return bytes.Compare(ipA.To4(), ipB.To4())
+}
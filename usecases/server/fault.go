@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// FaultKind is the kind of failure injected into simulated servers
+type FaultKind string
+
+const (
+	FaultSlow  FaultKind = "slow"  // delay every response by Delay
+	FaultError FaultKind = "error" // answer /status with 500
+	FaultDrop  FaultKind = "drop"  // close connections without responding
+	FaultCrash FaultKind = "crash" // stop the server After it starts or the fault is injected
+)
+
+// ErrInvalidFault is returned when a fault request is malformed
+var ErrInvalidFault = errors.New("invalid fault")
+
+// ErrFaultNotFound is returned when clearing a fault that does not exist
+var ErrFaultNotFound = errors.New("fault not found")
+
+// FaultInjector makes simulated servers misbehave to rehearse incidents
+type FaultInjector interface {
+	InjectFault(ctx context.Context, req InjectFaultRequest) (*Fault, error)
+	ListFaults(ctx context.Context) []Fault
+	ClearFault(ctx context.Context, faultID string) error
+	ClearFaults(ctx context.Context)
+}
+
+// Fault is an active fault. It targets either ServerIDs or a Percentage of the fleet.
+type Fault struct {
+	ID         string        `json:"id"`
+	Kind       FaultKind     `json:"kind"`
+	ServerIDs  []string      `json:"server_ids,omitempty"`
+	Percentage int           `json:"percentage,omitempty"`
+	Delay      time.Duration `json:"delay,omitempty"`
+	After      time.Duration `json:"after,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty"`
+}
+
+type InjectFaultRequest struct {
+	Kind            FaultKind `json:"kind" validate:"required,oneof=slow error drop crash"`
+	ServerIDs       []string  `json:"server_ids,omitempty"`                                    // target these servers
+	Percentage      int       `json:"percentage,omitempty" validate:"omitempty,min=1,max=100"` // or a random share of the fleet
+	DelayMs         int       `json:"delay_ms,omitempty" validate:"omitempty,min=1"`           // slow: response delay
+	AfterSeconds    int       `json:"after_seconds,omitempty" validate:"omitempty,min=0"`      // crash: delay before the server stops
+	DurationSeconds int       `json:"duration_seconds,omitempty" validate:"omitempty,min=1"`   // clear the fault automatically, 0 keeps it until cleared
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// ErrInvalidFieldset is returned when the requested fields or includes are unknown
+var ErrInvalidFieldset = errors.New("invalid fieldset")
+
+// Optional data added to listed servers with include=
+const (
+	IncludeLive   = "live"   // status reported by the server's provider right now
+	IncludeUptime = "uptime" // when the running server was last started
+)
+
+// MaxIncludePageSize is the largest page that can be listed with include=, since
+// every included server costs a call to its provider
+const MaxIncludePageSize = 100
+
+// includeConcurrency is the number of servers whose provider is asked for included data at the same time
+const includeConcurrency = 16
+
+// serverFields are the fields that can be selected with fields=, by their JSON names
+var serverFields = []string{
+	"id", "name", "status", "ipv4", "ipv6", "provider", "profile",
+	"description", "environment", "location", "owner", "labels", "interfaces",
+	"created_at", "updated_at",
+}
+
+// ServerView is a listed server reduced to the requested fields, plus any included data
+type ServerView map[string]interface{}
+
+// LiveInfo is the status of a server as reported by its provider
+type LiveInfo struct {
+	Status entity.ServerStatus `json:"status"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// UptimeInfo tells since when a running server has been up. It is null in the
+// response when the server is not running or its provider does not know.
+type UptimeInfo struct {
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
+}
+
+// ParseFields parses comma-separated field names. The ID is always returned, so it
+// need not be listed. An empty list selects every field.
+func ParseFields(s string) ([]string, error) {
+	return parseFieldList("field", s, serverFields)
+}
+
+// ParseIncludes parses comma-separated names of optional data, see IncludeLive and IncludeUptime
+func ParseIncludes(s string) ([]string, error) {
+	return parseFieldList("include", s, []string{IncludeLive, IncludeUptime})
+}
+
+// checkIncludePage rejects includes unless the page is bounded by from and to and
+// holds at most MaxIncludePageSize servers
+func checkIncludePage(includes []string, pagination ServerPagination) error {
+	if len(includes) == 0 {
+		return nil
+	}
+	if pagination.To <= pagination.From || pagination.To-pagination.From > MaxIncludePageSize {
+		return fmt.Errorf("%w: include requires a page of at most %d servers (set from and to)", ErrInvalidFieldset, MaxIncludePageSize)
+	}
+	return nil
+}
+
+func parseFieldList(kind string, s string, allowed []string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if !containsString(allowed, name) {
+			sorted := append([]string(nil), allowed...)
+			sort.Strings(sorted)
+			return nil, fmt.Errorf("%w: unknown %s %q (use %s)", ErrInvalidFieldset, kind, name, strings.Join(sorted, ", "))
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// viewServers reduces servers to fields, every field when it is empty, and adds the data in includes
+func (uc *ServerUsecase) viewServers(ctx context.Context, servers []entity.Server, fields []string, includes []string) []ServerView {
+	if len(fields) == 0 {
+		fields = serverFields
+	}
+
+	views := make([]ServerView, len(servers))
+	for i, server := range servers {
+		views[i] = serverView(server, fields)
+	}
+	if len(includes) == 0 {
+		return views
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, includeConcurrency)
+	for i := range servers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			for _, include := range includes {
+				switch include {
+				case IncludeLive:
+					views[i][IncludeLive] = uc.liveInfo(ctx, servers[i])
+				case IncludeUptime:
+					views[i][IncludeUptime] = uc.uptimeInfo(ctx, servers[i])
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	return views
+}
+
+// serverView returns the fields of server under their JSON names
+func serverView(server entity.Server, fields []string) ServerView {
+	view := ServerView{"id": server.ID}
+	for _, field := range fields {
+		switch field {
+		case "name":
+			view[field] = server.Name
+		case "status":
+			view[field] = server.Status
+		case "ipv4":
+			view[field] = server.IPv4
+		case "ipv6":
+			view[field] = server.IPv6
+		case "provider":
+			view[field] = server.Provider
+		case "profile":
+			view[field] = server.Profile
+		case "description":
+			view[field] = server.Description
+		case "environment":
+			view[field] = server.Environment
+		case "location":
+			view[field] = server.Location
+		case "owner":
+			view[field] = server.Owner
+		case "labels":
+			view[field] = server.Labels
+		case "interfaces":
+			view[field] = server.Interfaces
+		case "created_at":
+			view[field] = server.CreatedAt
+		case "updated_at":
+			view[field] = server.UpdatedAt
+		}
+	}
+	return view
+}
+
+func (uc *ServerUsecase) liveInfo(ctx context.Context, server entity.Server) LiveInfo {
+	provider, err := uc.providers.Get(server.Provider)
+	if err != nil {
+		return LiveInfo{Status: entity.StatusOffline, Error: err.Error()}
+	}
+	status, err := provider.GetServerStatus(ctx, server.ID)
+	if err != nil {
+		return LiveInfo{Status: status, Error: err.Error()}
+	}
+	return LiveInfo{Status: status}
+}
+
+func (uc *ServerUsecase) uptimeInfo(ctx context.Context, server entity.Server) *UptimeInfo {
+	provider, err := uc.providers.Get(server.Provider)
+	if err != nil {
+		return nil
+	}
+	reporter, ok := provider.(UptimeReporter)
+	if !ok {
+		return nil
+	}
+	startedAt, err := reporter.StartedAt(ctx, server.ID)
+	if err != nil || startedAt.IsZero() {
+		return nil
+	}
+	return &UptimeInfo{StartedAt: startedAt, UptimeSeconds: time.Since(startedAt).Seconds()}
+}
+
+// MarshalJSON writes the server views in place of the full servers when there are any
+func (r QueryServerResponse) MarshalJSON() ([]byte, error) {
+	type response QueryServerResponse
+	if r.Views == nil {
+		return json.Marshal(response(r))
+	}
+	return json.Marshal(struct {
+		Servers []ServerView `json:"servers"`
+		Total   int          `json:"total"`
+	}{r.Views, r.Total})
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFilter is returned when a server filter cannot be applied
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Validate checks the parts of the filter that need parsing
+func (f ServerFilter) Validate() error {
+	if _, err := ParseLabelSelector(f.Labels); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if _, err := ParseIPFilter(f.IPv4); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if _, err := ParseIPv6Filter(f.IPv6); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if _, err := ParseMACFilter(f.MAC); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if _, err := ParseQuery(f.Query); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	now := time.Now()
+	if _, err := f.CreatedRange(now); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if _, err := f.UpdatedRange(now); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return nil
+}
+
+// CreatedRange returns the range of creation times the filter accepts, with relative bounds resolved against now
+func (f ServerFilter) CreatedRange(now time.Time) (TimeRange, error) {
+	return parseTimeRange("created", f.CreatedFrom, f.CreatedTo, now)
+}
+
+// UpdatedRange returns the range of update times the filter accepts, with relative bounds resolved against now
+func (f ServerFilter) UpdatedRange(now time.Time) (TimeRange, error) {
+	return parseTimeRange("updated", f.UpdatedFrom, f.UpdatedTo, now)
+}
+
+// TimeRange is the half-open interval [From, To) of time. A zero bound leaves that side open.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// IsZero reports whether the range is unbounded
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// parseTimeRange parses the from and to bounds of the time filter named field, see parseTimeBound
+func parseTimeRange(field string, from, to string, now time.Time) (TimeRange, error) {
+	var r TimeRange
+	var err error
+	if r.From, err = parseTimeBound(from, now, false); err != nil {
+		return TimeRange{}, fmt.Errorf("%s_from: %v", field, err)
+	}
+	if r.To, err = parseTimeBound(to, now, true); err != nil {
+		return TimeRange{}, fmt.Errorf("%s_to: %v", field, err)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return TimeRange{}, fmt.Errorf("%s_from must be before %s_to", field, field)
+	}
+	return r, nil
+}
+
+// timeUnits are the units of relative time bounds
+var timeUnits = map[byte]time.Duration{
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// parseTimeBound parses a bound of a time filter: an RFC 3339 timestamp, a YYYY-MM-DD date in
+// server local time, "now", or an offset from now such as -7d, -12h or +30m (units m, h, d and w).
+// A date used as an upper bound includes the whole day. An empty bound is returned as the zero time.
+func parseTimeBound(value string, now time.Time, upper bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return time.Time{}, nil
+	case strings.EqualFold(value, "now"):
+		return now, nil
+	case value[0] == '-' || value[0] == '+':
+		if len(value) < 3 {
+			return time.Time{}, fmt.Errorf("invalid relative time %q (use an offset such as -7d, -12h, -30m or -2w)", value)
+		}
+		unit, ok := timeUnits[value[len(value)-1]]
+		amount, err := strconv.Atoi(value[1 : len(value)-1])
+		if !ok || err != nil || amount < 0 || amount > 100000 {
+			return time.Time{}, fmt.Errorf("invalid relative time %q (use an offset such as -7d, -12h, -30m or -2w)", value)
+		}
+		offset := time.Duration(amount) * unit
+		if value[0] == '-' {
+			offset = -offset
+		}
+		return now.Add(offset), nil
+	}
+
+	t, day, err := parseQueryTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use YYYY-MM-DD, RFC 3339 or an offset such as -7d)", value)
+	}
+	if day && upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// IPRange is an inclusive range of IPv4 addresses
+type IPRange struct {
+	From netip.Addr
+	To   netip.Addr
+}
+
+// Single reports whether the range holds a single address
+func (r IPRange) Single() bool {
+	return r.From == r.To
+}
+
+// Contains reports whether addr is in the range
+func (r IPRange) Contains(addr netip.Addr) bool {
+	return r.From.Compare(addr) <= 0 && addr.Compare(r.To) <= 0
+}
+
+// ParseIPFilter parses comma-separated IPv4 terms, any of which may match:
+//
+//	10.0.0.1               a single address
+//	10.0.0.0/8             a CIDR block
+//	10.0.0.1-10.0.0.50     an inclusive range
+//	192.168 or 10.         whole leading octets, the same as 192.168.0.0/16 and 10.0.0.0/8
+func ParseIPFilter(s string) ([]IPRange, error) {
+	var ranges []IPRange
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		r, err := parseIPTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseIPTerm(term string) (IPRange, error) {
+	if from, to, ok := strings.Cut(term, "-"); ok {
+		fromAddr, err := parseIPv4(strings.TrimSpace(from))
+		if err != nil {
+			return IPRange{}, fmt.Errorf("invalid IPv4 range %q: %w", term, err)
+		}
+		toAddr, err := parseIPv4(strings.TrimSpace(to))
+		if err != nil {
+			return IPRange{}, fmt.Errorf("invalid IPv4 range %q: %w", term, err)
+		}
+		if toAddr.Less(fromAddr) {
+			return IPRange{}, fmt.Errorf("invalid IPv4 range %q: %s is before %s", term, toAddr, fromAddr)
+		}
+		return IPRange{From: fromAddr, To: toAddr}, nil
+	}
+
+	if strings.Contains(term, "/") {
+		prefix, err := netip.ParsePrefix(term)
+		if err != nil || !prefix.Addr().Is4() {
+			return IPRange{}, fmt.Errorf("invalid IPv4 CIDR %q", term)
+		}
+		return prefixRange(prefix.Masked()), nil
+	}
+
+	if addr, err := parseIPv4(term); err == nil {
+		return IPRange{From: addr, To: addr}, nil
+	}
+
+	// Leading octets such as 192.168 or 10.
+	octets := strings.Split(strings.TrimSuffix(term, "."), ".")
+	if len(octets) < 4 {
+		padded := append(octets, make([]string, 4-len(octets))...)
+		for i := len(octets); i < 4; i++ {
+			padded[i] = "0"
+		}
+		if addr, err := parseIPv4(strings.Join(padded, ".")); err == nil {
+			return prefixRange(netip.PrefixFrom(addr, 8*len(octets))), nil
+		}
+	}
+
+	return IPRange{}, fmt.Errorf("invalid IPv4 filter %q (use an address, CIDR, range or leading octets)", term)
+}
+
+func parseIPv4(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if !addr.Is4() {
+		return netip.Addr{}, fmt.Errorf("%s is not an IPv4 address", s)
+	}
+	return addr, nil
+}
+
+// prefixRange returns the first and last address of prefix
+func prefixRange(prefix netip.Prefix) IPRange {
+	from := prefix.Addr().As4()
+	to := from
+	for bit := prefix.Bits(); bit < 32; bit++ {
+		to[bit/8] |= 1 << (7 - bit%8)
+	}
+	return IPRange{From: netip.AddrFrom4(from), To: netip.AddrFrom4(to)}
+}
+
+// ParseIPv6Filter parses comma-separated IPv6 addresses and CIDR blocks, any of which may match.
+// An address is returned as a /128 prefix.
+func ParseIPv6Filter(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		if strings.Contains(term, "/") {
+			prefix, err := netip.ParsePrefix(term)
+			if err != nil || !prefix.Addr().Is6() {
+				return nil, fmt.Errorf("invalid IPv6 CIDR %q", term)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(term)
+		if err != nil || !addr.Is6() || addr.Zone() != "" {
+			return nil, fmt.Errorf("invalid IPv6 filter %q (use an address or CIDR)", term)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, 128))
+	}
+	return prefixes, nil
+}
+
+// ParseMACFilter parses comma-separated MAC addresses, returned in canonical form
+func ParseMACFilter(s string) ([]string, error) {
+	var macs []string
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		mac, err := net.ParseMAC(term)
+		if err != nil || len(mac) != 6 {
+			return nil, fmt.Errorf("invalid MAC address %q", term)
+		}
+		macs = append(macs, mac.String())
+	}
+	return macs, nil
+}
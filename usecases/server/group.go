@@ -0,0 +1,383 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+var (
+	// ErrGroupNotFound is returned when a group does not exist
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrInvalidGroup is returned when a group request is malformed or names unknown servers
+	ErrInvalidGroup = errors.New("invalid group")
+	// ErrGroupExists is returned when a group name is already taken
+	ErrGroupExists = errors.New("group already exists")
+)
+
+const (
+	// maxGroupNameLength is the longest group name accepted, in characters
+	maxGroupNameLength = 100
+	// groupActionConcurrency is the number of members started or stopped at the same time
+	groupActionConcurrency = 8
+)
+
+// Group actions
+const (
+	GroupActionStart = "start"
+	GroupActionStop  = "stop"
+)
+
+// Outcomes of a group action for a member
+const (
+	GroupMemberDone      = "done"      // the server was started or stopped
+	GroupMemberUnchanged = "unchanged" // the server already had the requested status
+	GroupMemberFailed    = "failed"
+)
+
+// GroupService manages server groups and runs operations on all their members
+type GroupService interface {
+	CreateGroup(ctx context.Context, req CreateGroupRequest) (*entity.ServerGroup, error)
+	GetGroup(ctx context.Context, groupID string) (*entity.ServerGroup, error)
+	ListGroups(ctx context.Context) ([]entity.ServerGroup, error)
+	UpdateGroup(ctx context.Context, req UpdateGroupRequest) (*entity.ServerGroup, error)
+	DeleteGroup(ctx context.Context, groupID string) error
+
+	AddGroupMembers(ctx context.Context, groupID string, req GroupMembersRequest) (*entity.ServerGroup, error)
+	RemoveGroupMembers(ctx context.Context, groupID string, req GroupMembersRequest) (*entity.ServerGroup, error)
+
+	StartGroup(ctx context.Context, groupID string) (*GroupActionResponse, error)
+	StopGroup(ctx context.Context, groupID string) (*GroupActionResponse, error)
+	ExportGroup(ctx context.Context, groupID string, req ExportServerRequest, w io.Writer) error
+}
+
+// CreateGroupRequest represents the request to create a server group
+type CreateGroupRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description,omitempty"`
+	ServerIDs   []string `json:"server_ids,omitempty"`
+}
+
+// UpdateGroupRequest represents the request to rename or describe a server group
+type UpdateGroupRequest struct {
+	ID          string  `json:"-"`
+	Name        string  `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// GroupMembersRequest lists the servers to add to or remove from a group
+type GroupMembersRequest struct {
+	ServerIDs []string `json:"server_ids" binding:"required"`
+}
+
+// GroupActionResponse reports the outcome of a group action for every member
+type GroupActionResponse struct {
+	GroupID        string              `json:"group_id"`
+	Action         string              `json:"action"`
+	SucceededCount int                 `json:"succeeded_count"` // members done or unchanged
+	FailedCount    int                 `json:"failed_count"`
+	Results        []GroupMemberResult `json:"results"`
+}
+
+// GroupMemberResult is the outcome of a group action for one member
+type GroupMemberResult struct {
+	ServerID string              `json:"server_id"`
+	Name     string              `json:"name"`
+	Result   string              `json:"result"` // done, unchanged, failed
+	Status   entity.ServerStatus `json:"status"` // status of the server after the action
+	Error    string              `json:"error,omitempty"`
+}
+
+// GroupUsecase implements GroupService on top of the server use case,
+// so group actions go through the same providers as single-server changes
+type GroupUsecase struct {
+	groups  GroupRepository
+	servers *ServerUsecase
+}
+
+func NewGroupUsecase(groups GroupRepository, servers *ServerUsecase) *GroupUsecase {
+	return &GroupUsecase{
+		groups:  groups,
+		servers: servers,
+	}
+}
+
+func (uc *GroupUsecase) CreateGroup(ctx context.Context, req CreateGroupRequest) (*entity.ServerGroup, error) {
+	name, err := uc.checkGroupName(ctx, "", req.Name)
+	if err != nil {
+		return nil, err
+	}
+	serverIDs, err := uc.checkMembers(ctx, req.ServerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newGroupID()
+	if err != nil {
+		return nil, err
+	}
+	group := &entity.ServerGroup{
+		ID:          id,
+		Name:        name,
+		Description: req.Description,
+	}
+	err = uc.groups.Transaction(ctx, func(groups GroupRepository) error {
+		if err := groups.CreateGroup(ctx, group); err != nil {
+			return err
+		}
+		return groups.AddMembers(ctx, group.ID, serverIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.GetGroup(ctx, group.ID)
+}
+
+func (uc *GroupUsecase) GetGroup(ctx context.Context, groupID string) (*entity.ServerGroup, error) {
+	group, err := uc.groups.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, groupID)
+	}
+	return group, nil
+}
+
+func (uc *GroupUsecase) ListGroups(ctx context.Context) ([]entity.ServerGroup, error) {
+	return uc.groups.ListGroups(ctx)
+}
+
+func (uc *GroupUsecase) UpdateGroup(ctx context.Context, req UpdateGroupRequest) (*entity.ServerGroup, error) {
+	group, err := uc.GetGroup(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		name, err := uc.checkGroupName(ctx, group.ID, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		group.Name = name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+
+	if err := uc.groups.UpdateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteGroup removes a group. Its member servers are left untouched.
+func (uc *GroupUsecase) DeleteGroup(ctx context.Context, groupID string) error {
+	if _, err := uc.GetGroup(ctx, groupID); err != nil {
+		return err
+	}
+	return uc.groups.DeleteGroup(ctx, groupID)
+}
+
+func (uc *GroupUsecase) AddGroupMembers(ctx context.Context, groupID string, req GroupMembersRequest) (*entity.ServerGroup, error) {
+	if _, err := uc.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	serverIDs, err := uc.checkMembers(ctx, req.ServerIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.groups.AddMembers(ctx, groupID, serverIDs); err != nil {
+		return nil, err
+	}
+	return uc.GetGroup(ctx, groupID)
+}
+
+// RemoveGroupMembers removes servers from a group, IDs of servers not in it are ignored
+func (uc *GroupUsecase) RemoveGroupMembers(ctx context.Context, groupID string, req GroupMembersRequest) (*entity.ServerGroup, error) {
+	if _, err := uc.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if err := uc.groups.RemoveMembers(ctx, groupID, uniqueIDs(req.ServerIDs)); err != nil {
+		return nil, err
+	}
+	return uc.GetGroup(ctx, groupID)
+}
+
+// StartGroup starts every member of a group that is not running.
+// A member that fails does not stop the others, its error is reported in its result.
+func (uc *GroupUsecase) StartGroup(ctx context.Context, groupID string) (*GroupActionResponse, error) {
+	return uc.runGroupAction(ctx, groupID, GroupActionStart, entity.StatusOnline)
+}
+
+// StopGroup stops every running member of a group.
+// A member that fails does not stop the others, its error is reported in its result.
+func (uc *GroupUsecase) StopGroup(ctx context.Context, groupID string) (*GroupActionResponse, error) {
+	return uc.runGroupAction(ctx, groupID, GroupActionStop, entity.StatusOffline)
+}
+
+// ExportGroup writes the members of a group matching req to w, as ExportServers does
+func (uc *GroupUsecase) ExportGroup(ctx context.Context, groupID string, req ExportServerRequest, w io.Writer) error {
+	group, err := uc.GetGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	req.Filter.Group = group.ID
+	return uc.servers.ExportServers(ctx, req, w)
+}
+
+// runGroupAction sets the status of every member of a group through its provider
+func (uc *GroupUsecase) runGroupAction(ctx context.Context, groupID string, action string, status entity.ServerStatus) (*GroupActionResponse, error) {
+	group, err := uc.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := uc.servers.serverRepo.FindByIDsOrNames(ctx, group.ServerIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+
+	response := &GroupActionResponse{
+		GroupID: group.ID,
+		Action:  action,
+		Results: make([]GroupMemberResult, len(members)),
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, groupActionConcurrency)
+	for i := range members {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			response.Results[i] = uc.setMemberStatus(ctx, &members[i], status)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range response.Results {
+		if result.Result == GroupMemberFailed {
+			response.FailedCount++
+		} else {
+			response.SucceededCount++
+		}
+	}
+	return response, nil
+}
+
+func (uc *GroupUsecase) setMemberStatus(ctx context.Context, server *entity.Server, status entity.ServerStatus) GroupMemberResult {
+	result := GroupMemberResult{
+		ServerID: server.ID,
+		Name:     server.Name,
+		Status:   server.Status,
+	}
+
+	// The stored status may be stale, so a member is only skipped when its provider
+	// reports the requested status. When the provider cannot tell, the update is applied.
+	provider, err := uc.servers.providers.Get(server.Provider)
+	if err != nil {
+		result.Result = GroupMemberFailed
+		result.Error = err.Error()
+		return result
+	}
+	if live, err := provider.GetServerStatus(ctx, server.ID); err == nil {
+		server.Status = live
+		result.Status = live
+		if live == status {
+			result.Result = GroupMemberUnchanged
+			return result
+		}
+	}
+
+	if err := uc.servers.applyUpdate(ctx, server, UpdateServerRequest{ID: server.ID, Status: status}); err != nil {
+		result.Result = GroupMemberFailed
+		result.Error = err.Error()
+		return result
+	}
+	result.Result = GroupMemberDone
+	result.Status = status
+	return result
+}
+
+// checkGroupName returns the trimmed name, ensuring it is valid and not used by a group other than groupID
+func (uc *GroupUsecase) checkGroupName(ctx context.Context, groupID string, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidGroup)
+	}
+	if len([]rune(name)) > maxGroupNameLength {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidGroup, maxGroupNameLength)
+	}
+
+	existing, err := uc.groups.GetGroupByName(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.ID != groupID {
+		return "", fmt.Errorf("%w: %s", ErrGroupExists, name)
+	}
+	return name, nil
+}
+
+// checkMembers returns serverIDs without duplicates, ensuring every server exists
+func (uc *GroupUsecase) checkMembers(ctx context.Context, serverIDs []string) ([]string, error) {
+	ids := uniqueIDs(serverIDs)
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	servers, err := uc.servers.serverRepo.FindByIDsOrNames(ctx, ids, nil)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		found[server.ID] = true
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: unknown servers %s", ErrInvalidGroup, strings.Join(missing, ", "))
+	}
+	return ids, nil
+}
+
+// uniqueIDs returns the non-empty IDs of ids, trimmed and without duplicates, in their original order
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+// newGroupID returns a random 128-bit hex ID
+func newGroupID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate group ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/xuri/excelize/v2"
+)
+
+// ImportFormat is a file format servers can be imported from
+type ImportFormat string
+
+const (
+	ImportXLSX ImportFormat = "xlsx"
+	ImportCSV  ImportFormat = "csv"
+	ImportJSON ImportFormat = "json"
+)
+
+// ErrUnsupportedImportFormat is returned when a file's format cannot be detected
+var ErrUnsupportedImportFormat = errors.New("unsupported import format")
+
+// DetectImportFormat detects the format of an uploaded file from its extension,
+// falling back to its MIME type when the extension is unknown
+func DetectImportFormat(filename string, contentType string) (ImportFormat, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		return ImportXLSX, nil
+	case ".xls":
+		return "", errLegacyExcel(filename)
+	case ".csv":
+		return ImportCSV, nil
+	case ".json":
+		return ImportJSON, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return ImportXLSX, nil
+	case "application/vnd.ms-excel":
+		return "", errLegacyExcel(filename)
+	case "text/csv", "application/csv":
+		return ImportCSV, nil
+	case "application/json":
+		return ImportJSON, nil
+	}
+
+	return "", fmt.Errorf("%w: %s (only .xlsx, .csv and .json files are allowed)", ErrUnsupportedImportFormat, filename)
+}
+
+// errLegacyExcel rejects Excel 97-2003 workbooks, which cannot be read
+func errLegacyExcel(filename string) error {
+	return fmt.Errorf("%w: %s is an Excel 97-2003 workbook (save it as .xlsx and upload it again)", ErrUnsupportedImportFormat, filename)
+}
+
+// ImportMode decides what happens to rows whose server already exists
+type ImportMode string
+
+const (
+	// ImportCreateOnly fails rows whose ID or name already exists
+	ImportCreateOnly ImportMode = "create-only"
+	// ImportUpsert updates existing servers with the non-empty fields of their row
+	ImportUpsert ImportMode = "upsert"
+	// ImportReplace upserts, then deletes the servers missing from the file.
+	// Nothing is deleted when any row fails.
+	ImportReplace ImportMode = "replace"
+)
+
+// ParseImportMode validates a mode name, empty defaults to create-only
+func ParseImportMode(name string) (ImportMode, error) {
+	switch mode := ImportMode(name); mode {
+	case "":
+		return ImportCreateOnly, nil
+	case ImportCreateOnly, ImportUpsert, ImportReplace:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported import mode %q (use create-only, upsert or replace)", name)
+	}
+}
+
+// Import columns, as recognised from header names
+const (
+	importColumnID       = "id"
+	importColumnName     = "name"
+	importColumnIPv4     = "ipv4"
+	importColumnStatus   = "status"
+	importColumnProvider = "provider"
+
+	importColumnIPv6       = "ipv6"
+	importColumnInterfaces = "interfaces" // name=address|address pairs separated by semicolons, primary first
+
+	importColumnDescription = "description"
+	importColumnEnvironment = "environment"
+	importColumnLocation    = "location"
+	importColumnOwner       = "owner"
+	importColumnLabels      = "labels" // key=value pairs separated by commas
+)
+
+// importColumns are the import columns in template order
+var importColumns = []string{
+	importColumnID, importColumnName, importColumnIPv4, importColumnStatus, importColumnProvider,
+	importColumnDescription, importColumnEnvironment, importColumnLocation, importColumnOwner, importColumnLabels,
+	importColumnIPv6, importColumnInterfaces,
+}
+
+// importHeaderAliases maps normalized header names to import columns
+var importHeaderAliases = map[string]string{
+	"id":         importColumnID,
+	"serverid":   importColumnID,
+	"name":       importColumnName,
+	"servername": importColumnName,
+	"ipv4":       importColumnIPv4,
+	"ip":         importColumnIPv4,
+	"ipaddress":  importColumnIPv4,
+	"status":     importColumnStatus,
+	"provider":   importColumnProvider,
+
+	"description": importColumnDescription,
+	"environment": importColumnEnvironment,
+	"env":         importColumnEnvironment,
+	"location":    importColumnLocation,
+	"owner":       importColumnOwner,
+	"labels":      importColumnLabels,
+	"tags":        importColumnLabels,
+
+	"ipv6":              importColumnIPv6,
+	"ipv6address":       importColumnIPv6,
+	"interfaces":        importColumnInterfaces,
+	"networkinterfaces": importColumnInterfaces,
+	"nics":              importColumnInterfaces,
+}
+
+// importColumn returns the import column a header refers to, or "" if it is not recognised.
+// Case, spaces, dashes and underscores are ignored, so "Server ID" and "server_id" both map to id.
+func importColumn(header string) string {
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(header)))
+
+	return importHeaderAliases[normalized]
+}
+
+// ImportSheet is the content of an import file
+type ImportSheet struct {
+	Headers []string // header row as written in the file
+	Rows    []ImportRow
+
+	rowOffset int // added to a row's Line to get its row in a report
+}
+
+// ImportRow is a data row of an import file keyed by import column
+type ImportRow struct {
+	Line   int      // row number in a spreadsheet or CSV file, index + 1 in a JSON array
+	Cells  []string // raw values in header order
+	Fields map[string]string
+}
+
+// Get returns the trimmed value of column
+func (r ImportRow) Get(column string) string {
+	return strings.TrimSpace(r.Fields[column])
+}
+
+// Importer reads the header and data rows of an import file
+type Importer interface {
+	ReadSheet(r io.Reader) (*ImportSheet, error)
+}
+
+// NewImporter creates an importer for format
+func NewImporter(format ImportFormat) (Importer, error) {
+	switch format {
+	case ImportXLSX:
+		return xlsxImporter{}, nil
+	case ImportCSV:
+		return csvImporter{}, nil
+	case ImportJSON:
+		return jsonImporter{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedImportFormat, format)
+	}
+}
+
+// sheetFromTable maps tabular records to import rows using the header record.
+// lines holds the line number of each record in the file, nil when records are consecutive rows.
+func sheetFromTable(records [][]string, lines []int) (*ImportSheet, error) {
+	if len(records) < 2 {
+		return nil, fmt.Errorf("file must contain at least headers and one data row")
+	}
+
+	columns := make([]string, len(records[0]))
+	found := make(map[string]bool)
+	for i, header := range records[0] {
+		columns[i] = importColumn(header)
+		found[columns[i]] = true
+	}
+	for _, required := range []string{importColumnID, importColumnName} {
+		if !found[required] {
+			return nil, fmt.Errorf("missing required column %q in header row", required)
+		}
+	}
+	if !found[importColumnIPv4] && !found[importColumnIPv6] && !found[importColumnInterfaces] {
+		return nil, fmt.Errorf("missing address column in header row (use %q, %q or %q)", importColumnIPv4, importColumnIPv6, importColumnInterfaces)
+	}
+
+	sheet := &ImportSheet{
+		Headers: records[0],
+		Rows:    make([]ImportRow, 0, len(records)-1),
+	}
+	for i, record := range records[1:] {
+		line := i + 2
+		if lines != nil {
+			line = lines[i+1]
+		}
+		row := ImportRow{Line: line, Cells: make([]string, len(columns)), Fields: make(map[string]string)}
+		empty := true
+		for j, value := range record {
+			if j < len(row.Cells) {
+				row.Cells[j] = value
+			}
+			if j >= len(columns) || columns[j] == "" {
+				continue
+			}
+			row.Fields[columns[j]] = value
+			if strings.TrimSpace(value) != "" {
+				empty = false
+			}
+		}
+		if empty {
+			continue // Skip blank lines
+		}
+		sheet.Rows = append(sheet.Rows, row)
+	}
+
+	return sheet, nil
+}
+
+type xlsxImporter struct{}
+
+func (xlsxImporter) ReadSheet(r io.Reader) (*ImportSheet, error) {
+	// Open Excel file
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Printf("Error closing Excel file: %v\n", err)
+		}
+	}()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("Excel file must contain at least one sheet")
+	}
+
+	// Get all rows from the first sheet
+	records, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows from Excel file: %w", err)
+	}
+
+	return sheetFromTable(records, nil)
+}
+
+type csvImporter struct{}
+
+func (csvImporter) ReadSheet(r io.Reader) (*ImportSheet, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Tolerate ragged rows, missing cells are empty
+	reader.TrimLeadingSpace = true
+
+	// Blank lines are skipped by the reader, so line numbers are taken from
+	// the position of each record rather than its index
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	// Drop a UTF-8 byte order mark left by spreadsheet tools
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+
+	return sheetFromTable(records, lines)
+}
+
+// jsonImporter reads an array of objects, or an object with a "servers" array
+type jsonImporter struct{}
+
+func (jsonImporter) ReadSheet(r io.Reader) (*ImportSheet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON file: %w", err)
+	}
+
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		var wrapped struct {
+			Servers []map[string]interface{} `json:"servers"`
+		}
+		if wrappedErr := json.Unmarshal(data, &wrapped); wrappedErr != nil || wrapped.Servers == nil {
+			return nil, fmt.Errorf("failed to parse JSON file: %w", err)
+		}
+		objects = wrapped.Servers
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("file must contain at least one server")
+	}
+
+	rows := make([]ImportRow, 0, len(objects))
+	found := make(map[string]bool)
+	for i, object := range objects {
+		row := ImportRow{Line: i + 1, Fields: make(map[string]string)}
+		for key, value := range object {
+			column := importColumn(key)
+			if column == "" || value == nil {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				row.Fields[column] = v
+			case map[string]interface{}:
+				labels := make(entity.Labels, len(v))
+				for key, labelValue := range v {
+					labels[key] = fmt.Sprint(labelValue)
+				}
+				row.Fields[column] = labels.String()
+			case []interface{}:
+				// Interfaces as exported to JSON, an array of objects
+				var interfaces entity.NetworkInterfaces
+				encoded, _ := json.Marshal(v)
+				if column == importColumnInterfaces && json.Unmarshal(encoded, &interfaces) == nil {
+					row.Fields[column] = interfaces.String()
+				} else {
+					row.Fields[column] = fmt.Sprint(v)
+				}
+			case float64:
+				row.Fields[column] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				row.Fields[column] = fmt.Sprint(v)
+			}
+			found[column] = true
+		}
+		rows = append(rows, row)
+	}
+
+	// Objects have no header row, the recognised columns are laid out in their usual order
+	sheet := &ImportSheet{Rows: rows, rowOffset: 1}
+	for _, column := range importColumns {
+		if found[column] {
+			sheet.Headers = append(sheet.Headers, column)
+		}
+	}
+	for i := range sheet.Rows {
+		sheet.Rows[i].Cells = make([]string, len(sheet.Headers))
+		for j, column := range sheet.Headers {
+			sheet.Rows[i].Cells[j] = sheet.Rows[i].Fields[column]
+		}
+	}
+
+	return sheet, nil
+}
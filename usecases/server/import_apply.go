@@ -0,0 +1,691 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// importBatchSize is the number of new servers provisioned and inserted together
+const importBatchSize = 500
+
+// importSheet imports the rows of sheet and, when opts.Report is set, attaches the annotated report
+func (uc *ServerUsecase) importSheet(ctx context.Context, sheet *ImportSheet, opts ImportOptions, progress func(result *ImportRespose)) (*ImportRespose, error) {
+	result, err := uc.importRows(ctx, sheet.Rows, opts, progress)
+	if result == nil || !opts.Report {
+		return result, err
+	}
+
+	var buf bytes.Buffer
+	if reportErr := writeImportReport(&buf, sheet, result); reportErr != nil {
+		return result, errors.Join(err, reportErr)
+	}
+	result.Report = buf.Bytes()
+	return result, err
+}
+
+// importRows imports parsed rows. progress, when set, is called with the running totals as rows complete.
+// Import stops between rows once ctx is cancelled, returning the partial result with the context error.
+// In atomic mode nothing is imported unless every row succeeds.
+func (uc *ServerUsecase) importRows(ctx context.Context, rows []ImportRow, opts ImportOptions, progress func(result *ImportRespose)) (*ImportRespose, error) {
+	plan, err := uc.planImport(ctx, rows, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportRespose{
+		Mode:           opts.Mode,
+		DryRun:         opts.DryRun,
+		Atomic:         opts.Atomic,
+		SuccessServers: make([]string, 0),
+		FailureServers: make([]string, 0),
+		SkippedServers: make([]string, 0),
+		DeletedServers: make([]string, 0),
+	}
+
+	// Rows settled by planning are recorded first, the rest is applied below
+	pending := make([]importPlanItem, 0, len(plan))
+	for _, item := range plan {
+		report := item.report()
+
+		switch item.action {
+		case importActionFail:
+			result.addFailure(item.line, item.req.ID, item.req.Name, strings.Join(report.Errors, "; "), item.columns...)
+			if opts.DryRun {
+				result.WouldFail = append(result.WouldFail, report)
+			}
+		case importActionSkip:
+			result.addSkipped(item.line, item.req.ID, item.req.Name, item.reason)
+			if opts.DryRun {
+				result.WouldSkip = append(result.WouldSkip, report)
+			}
+		case importActionUnchanged:
+			result.UnchangedCount++
+			result.addSuccess(item.line, item.req.ID, item.req.Name, ImportRowUnchanged)
+			if opts.DryRun {
+				result.WouldSkip = append(result.WouldSkip, report)
+			}
+		default:
+			pending = append(pending, item)
+		}
+	}
+
+	// Replace mode deletes the servers missing from the file, unless a row failed
+	// and the file may not describe the complete fleet
+	var missing []entity.Server
+	if opts.Mode == ImportReplace && result.FailureCount == 0 {
+		missing, err = uc.missingServers(ctx, plan)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.DryRun {
+		for _, item := range pending {
+			if item.action == importActionUpdate {
+				result.UpdatedCount++
+				result.WouldUpdate = append(result.WouldUpdate, item.report())
+				result.addSuccess(item.line, item.req.ID, item.req.Name, ImportRowUpdated)
+			} else {
+				result.CreatedCount++
+				result.WouldCreate = append(result.WouldCreate, item.report())
+				result.addSuccess(item.line, item.req.ID, item.req.Name, ImportRowCreated)
+			}
+		}
+		for _, server := range missing {
+			result.DeletedCount++
+			result.DeletedServers = append(result.DeletedServers, fmt.Sprintf("%s:%s", server.ID, server.Name))
+			result.WouldDelete = append(result.WouldDelete, ImportRowReport{ID: server.ID, Name: server.Name})
+		}
+		return result, nil
+	}
+
+	if !opts.Atomic {
+		err = uc.applyImport(ctx, pending, missing, result, progress, nil)
+		return result, err
+	}
+
+	if result.FailureCount > 0 {
+		for _, item := range pending {
+			result.addFailure(item.line, item.req.ID, item.req.Name, "not imported because other rows are invalid")
+		}
+		return result, nil
+	}
+
+	return uc.applyImportAtomically(ctx, pending, missing, result, progress)
+}
+
+// applyImport updates, creates and deletes servers as planned, recording the outcome in result.
+// When undo is set the import is atomic: it stops at the first failure, and undo collects
+// the functions reverting provider changes, which a database rollback cannot undo.
+func (uc *ServerUsecase) applyImport(ctx context.Context, items []importPlanItem, missing []entity.Server, result *ImportRespose, progress func(result *ImportRespose), undo *[]func(ctx context.Context)) error {
+	atomic := undo != nil
+	reportProgress := func() {
+		if progress != nil {
+			progress(result)
+		}
+	}
+	reportProgress()
+
+	// Updates follow UpdateServer rules one by one, since each may start or stop a server
+	creates := make([]importPlanItem, 0, len(items))
+	for _, item := range items {
+		if item.action != importActionUpdate {
+			creates = append(creates, item)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		original := *item.existing
+		err := uc.applyUpdate(ctx, item.existing, UpdateServerRequest{
+			ID:     item.req.ID,
+			Name:   item.req.Name,
+			IPv4:   item.req.IPv4,
+			IPv6:   item.req.IPv6,
+			Status: item.req.Status,
+
+			Description: optionalString(item.req.Description),
+			Environment: optionalString(item.req.Environment),
+			Location:    optionalString(item.req.Location),
+			Owner:       optionalString(item.req.Owner),
+			Labels:      item.req.Labels,
+
+			Interfaces: item.req.Interfaces,
+		})
+		if err != nil {
+			if atomic {
+				return fmt.Errorf("%s:%s - %w", item.req.ID, item.req.Name, err)
+			}
+			result.addFailure(item.line, item.req.ID, item.req.Name, err.Error())
+			reportProgress()
+			continue
+		}
+		if atomic {
+			*undo = append(*undo, func(ctx context.Context) { uc.restoreServer(ctx, &original) })
+		}
+
+		result.UpdatedCount++
+		result.addSuccess(item.line, item.req.ID, item.req.Name, ImportRowUpdated)
+		reportProgress()
+	}
+
+	// New servers are provisioned one by one and inserted in batches
+	for start := 0; start < len(creates); start += importBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch := creates[start:min(start+importBatchSize, len(creates))]
+		servers := make([]entity.Server, 0, len(batch))
+		lines := make([]int, 0, len(batch))
+		for _, item := range batch {
+			if ctx.Err() != nil {
+				break // Servers provisioned so far are still saved below
+			}
+			req := item.req
+			req.Interfaces = item.interfaces
+			server, err := uc.provisionServer(ctx, req)
+			if err != nil {
+				if atomic {
+					return fmt.Errorf("%s:%s - %w", item.req.ID, item.req.Name, err)
+				}
+				result.addFailure(item.line, item.req.ID, item.req.Name, err.Error())
+				continue
+			}
+			servers = append(servers, *server)
+			lines = append(lines, item.line)
+			if atomic {
+				*undo = append(*undo, func(ctx context.Context) { uc.deprovisionServer(ctx, server) })
+			}
+		}
+
+		insertCtx := ctx
+		if !atomic {
+			insertCtx = context.WithoutCancel(ctx)
+		}
+		if err := uc.insertServers(insertCtx, servers, lines, result, atomic); err != nil {
+			return err
+		}
+		reportProgress()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Rows can still fail while applied, after which the file no longer describes the fleet
+	if result.FailureCount > 0 {
+		for _, server := range missing {
+			result.addSkipped(0, server.ID, server.Name, "not deleted because other rows failed")
+		}
+		missing = nil
+	}
+
+	for _, server := range missing {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := uc.DeleteServer(ctx, server.ID); err != nil {
+			if atomic {
+				return fmt.Errorf("%s:%s - %w", server.ID, server.Name, err)
+			}
+			result.addFailure(0, server.ID, server.Name, err.Error())
+			continue
+		}
+		if atomic {
+			deleted := server
+			*undo = append(*undo, func(ctx context.Context) { uc.restoreServer(ctx, &deleted) })
+		}
+
+		result.DeletedCount++
+		result.DeletedServers = append(result.DeletedServers, fmt.Sprintf("%s:%s", server.ID, server.Name))
+	}
+	reportProgress()
+
+	return nil
+}
+
+// applyImportAtomically applies the import in one database transaction.
+// On any failure the transaction is rolled back, provider changes are reverted
+// and every row is reported as failed.
+func (uc *ServerUsecase) applyImportAtomically(ctx context.Context, items []importPlanItem, missing []entity.Server, result *ImportRespose, progress func(result *ImportRespose)) (*ImportRespose, error) {
+	planned := *result
+
+	var undo []func(ctx context.Context)
+	err := uc.serverRepo.Transaction(ctx, func(repo Repository) error {
+		tx := &ServerUsecase{serverRepo: repo, providers: uc.providers}
+		return tx.applyImport(ctx, items, missing, result, progress, &undo)
+	})
+	if err == nil {
+		return result, nil
+	}
+
+	// Provider changes are reverted even when ctx was cancelled
+	revertCtx := context.WithoutCancel(ctx)
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i](revertCtx)
+	}
+
+	*result = planned
+	for _, item := range items {
+		result.addFailure(item.line, item.req.ID, item.req.Name, fmt.Sprintf("rolled back: %v", err))
+	}
+	if progress != nil {
+		progress(result)
+	}
+
+	return result, ctx.Err()
+}
+
+// insertServers saves provisioned servers, read from the given file lines, with batched inserts.
+// Outside atomic mode a failed batch is retried row by row, so one bad row only fails itself;
+// servers that cannot be saved are removed from their provider again.
+func (uc *ServerUsecase) insertServers(ctx context.Context, servers []entity.Server, lines []int, result *ImportRespose, atomic bool) error {
+	err := uc.serverRepo.CreateBatch(ctx, servers, importBatchSize)
+	if err != nil && atomic {
+		return err
+	}
+
+	for i := range servers {
+		server := &servers[i]
+		if err != nil {
+			if createErr := uc.serverRepo.Create(ctx, server); createErr != nil {
+				uc.deprovisionServer(ctx, server)
+				result.addFailure(lines[i], server.ID, server.Name, createErr.Error())
+				continue
+			}
+		}
+		result.CreatedCount++
+		result.addSuccess(lines[i], server.ID, server.Name, ImportRowCreated)
+	}
+
+	return nil
+}
+
+// missingServers returns the existing servers that no row of plan refers to
+func (uc *ServerUsecase) missingServers(ctx context.Context, plan []importPlanItem) ([]entity.Server, error) {
+	inFile := make(map[string]bool, len(plan))
+	for _, item := range plan {
+		inFile[item.req.ID] = true
+	}
+
+	var missing []entity.Server
+	err := uc.eachServer(ctx, QueryServerRequest{}, func(server entity.Server) error {
+		if !inFile[server.ID] {
+			missing = append(missing, server)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+// deprovisionServer removes a server that was never saved from its provider
+func (uc *ServerUsecase) deprovisionServer(ctx context.Context, server *entity.Server) {
+	if provider, err := uc.providers.Get(server.Provider); err == nil {
+		_ = provider.DeleteServer(ctx, server.ID)
+	}
+}
+
+// restoreServer brings a server's provider back to a previous state of the server,
+// recreating it when it was deleted
+func (uc *ServerUsecase) restoreServer(ctx context.Context, server *entity.Server) {
+	provider, err := uc.providers.Get(server.Provider)
+	if err != nil {
+		return
+	}
+	if _, err := provider.GetServerStatus(ctx, server.ID); err != nil {
+		_ = provider.CreateServer(ctx, server)
+		return
+	}
+	_ = provider.UpdateServer(ctx, server)
+}
+
+// addSuccess records a row that was created, updated or left unchanged
+func (r *ImportRespose) addSuccess(line int, id, name string, status ImportRowStatus) {
+	r.SuccessCount++
+	r.SuccessServers = append(r.SuccessServers, fmt.Sprintf("%s:%s", id, name))
+	r.setOutcome(line, ImportRowOutcome{Status: status})
+}
+
+// addFailure records a failed row, or a failed deletion when line is 0.
+// columns are the import columns holding the offending values, if known.
+func (r *ImportRespose) addFailure(line int, id, name, reason string, columns ...string) {
+	r.FailureCount++
+	r.FailureServers = append(r.FailureServers, fmt.Sprintf("%s:%s - %s", id, name, reason))
+	r.setOutcome(line, ImportRowOutcome{Status: ImportRowFailed, Message: reason, Columns: columns})
+}
+
+func (r *ImportRespose) addSkipped(line int, id, name, reason string) {
+	r.SkippedCount++
+	r.SkippedServers = append(r.SkippedServers, fmt.Sprintf("%s:%s - %s", id, name, reason))
+	r.setOutcome(line, ImportRowOutcome{Status: ImportRowSkipped, Message: reason})
+}
+
+func (r *ImportRespose) setOutcome(line int, outcome ImportRowOutcome) {
+	if line <= 0 {
+		return
+	}
+	if r.Outcomes == nil {
+		r.Outcomes = make(map[int]ImportRowOutcome)
+	}
+	r.Outcomes[line] = outcome
+}
+
+type importAction int
+
+const (
+	importActionCreate importAction = iota
+	importActionUpdate
+	importActionUnchanged
+	importActionSkip
+	importActionFail
+)
+
+// importPlanItem is the validated form of an import row and what importing it will do
+type importPlanItem struct {
+	line     int
+	req      CreateServerRequest
+	existing *entity.Server // server updated by the row
+	action   importAction
+	errors   []string
+	columns  []string // import columns holding invalid values
+	changes  []string
+	reason   string
+
+	interfaces entity.NetworkInterfaces // interfaces of the server once imported
+}
+
+func (item *importPlanItem) addError(column, message string) {
+	item.errors = append(item.errors, message)
+	item.columns = append(item.columns, column)
+}
+
+func (item importPlanItem) report() ImportRowReport {
+	return ImportRowReport{
+		Line:    item.line,
+		ID:      item.req.ID,
+		Name:    item.req.Name,
+		Errors:  item.errors,
+		Changes: item.changes,
+		Reason:  item.reason,
+	}
+}
+
+// planImport validates every row and decides what importing it will do. It has no side effects.
+// A row is skipped when it repeats an earlier row exactly, and fails when it is invalid,
+// conflicts with an existing server or reuses the ID or name of a different row in the file.
+// Outside create-only mode a row whose ID exists updates that server instead of failing.
+func (uc *ServerUsecase) planImport(ctx context.Context, rows []ImportRow, mode ImportMode) ([]importPlanItem, error) {
+	// Existing servers sharing an ID or name with the file are loaded in one set-based query
+	ids := make([]string, 0, len(rows))
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if id := row.Get(importColumnID); id != "" {
+			ids = append(ids, id)
+		}
+		if name := row.Get(importColumnName); name != "" {
+			names = append(names, name)
+		}
+	}
+	servers, err := uc.serverRepo.FindByIDsOrNames(ctx, ids, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing servers: %w", err)
+	}
+	byID := make(map[string]*entity.Server, len(servers))
+	byName := make(map[string]*entity.Server, len(servers))
+	for i := range servers {
+		byID[servers[i].ID] = &servers[i]
+		byName[servers[i].Name] = &servers[i]
+	}
+
+	plan := make([]importPlanItem, 0, len(rows))
+	seenIDs := make(map[string]int)   // ID -> index in plan of the first row using it
+	seenNames := make(map[string]int) // name -> index in plan of the first row using it
+
+	for _, row := range rows {
+		item := importPlanItem{
+			line: row.Line,
+			req: CreateServerRequest{
+				ID:       row.Get(importColumnID),
+				Name:     row.Get(importColumnName),
+				IPv4:     row.Get(importColumnIPv4),
+				IPv6:     row.Get(importColumnIPv6),
+				Status:   entity.ServerStatus(strings.ToUpper(row.Get(importColumnStatus))),
+				Provider: row.Get(importColumnProvider),
+
+				Description: row.Get(importColumnDescription),
+				Environment: row.Get(importColumnEnvironment),
+				Location:    row.Get(importColumnLocation),
+				Owner:       row.Get(importColumnOwner),
+			},
+		}
+		if value := row.Get(importColumnLabels); value != "" {
+			labels, err := entity.ParseLabels(value)
+			if err != nil {
+				item.addError(importColumnLabels, err.Error())
+			}
+			item.req.Labels = labels
+		}
+		if value := row.Get(importColumnInterfaces); value != "" {
+			interfaces, err := entity.ParseNetworkInterfaces(value)
+			if err != nil {
+				item.addError(importColumnInterfaces, err.Error())
+			}
+			item.req.Interfaces = interfaces
+		}
+		req := item.req
+
+		// Field validation
+		if req.ID == "" {
+			item.addError(importColumnID, "missing ID")
+		}
+		if req.Name == "" {
+			item.addError(importColumnName, "missing name")
+		}
+		if req.IPv4 == "" && req.IPv6 == "" && req.Interfaces == nil && row.Get(importColumnInterfaces) == "" {
+			item.addError(importColumnIPv4, "missing IPv4, IPv6 or interfaces")
+		}
+		if ip := net.ParseIP(req.IPv4); req.IPv4 != "" && (ip == nil || ip.To4() == nil || strings.Contains(req.IPv4, ":")) {
+			item.addError(importColumnIPv4, fmt.Sprintf("invalid IPv4 address %q", req.IPv4))
+		}
+		if ip := net.ParseIP(req.IPv6); req.IPv6 != "" && (ip == nil || !strings.Contains(req.IPv6, ":")) {
+			item.addError(importColumnIPv6, fmt.Sprintf("invalid IPv6 address %q", req.IPv6))
+		}
+		if req.Status != "" && req.Status != entity.StatusOnline && req.Status != entity.StatusOffline {
+			item.addError(importColumnStatus, fmt.Sprintf("invalid status %q", req.Status))
+		}
+		if _, err := uc.providers.Get(req.Provider); err != nil {
+			item.addError(importColumnProvider, err.Error())
+		}
+
+		// Duplicates within the file
+		if first, ok := seenIDs[req.ID]; ok && req.ID != "" {
+			if reflect.DeepEqual(plan[first].req, req) {
+				item.action = importActionSkip
+				item.reason = fmt.Sprintf("duplicate of line %d", plan[first].line)
+				plan = append(plan, item)
+				continue
+			}
+			item.addError(importColumnID, fmt.Sprintf("ID also used on line %d", plan[first].line))
+		}
+		if first, ok := seenNames[req.Name]; ok && req.Name != "" {
+			item.addError(importColumnName, fmt.Sprintf("name also used on line %d", plan[first].line))
+		}
+		if _, ok := seenIDs[req.ID]; !ok && req.ID != "" {
+			seenIDs[req.ID] = len(plan)
+		}
+		if _, ok := seenNames[req.Name]; !ok && req.Name != "" {
+			seenNames[req.Name] = len(plan)
+		}
+
+		// Conflicts with existing servers
+		existing := byID[req.ID]
+		if existing != nil {
+			if mode == ImportCreateOnly {
+				item.addError(importColumnID, "ID already exists")
+			} else {
+				item.existing = existing
+				uc.checkImportUpdate(&item, existing)
+				item.changes = importChanges(existing, req)
+			}
+		}
+		if len(item.errors) == 0 {
+			var err error
+			if item.existing != nil {
+				if req.Interfaces != nil || req.IPv4 != "" || req.IPv6 != "" {
+					item.interfaces, err = updateInterfaces(existing, UpdateServerRequest{IPv4: req.IPv4, IPv6: req.IPv6, Interfaces: req.Interfaces})
+				}
+			} else {
+				item.interfaces, err = resolveInterfaces(req.IPv4, req.IPv6, req.Interfaces)
+			}
+			if err != nil {
+				item.addError(importAddressColumn(req), err.Error())
+			}
+		}
+		if server := byName[req.Name]; server != nil && (mode == ImportCreateOnly || server.ID != req.ID) {
+			item.addError(importColumnName, "name already exists")
+		}
+
+		switch {
+		case len(item.errors) > 0:
+			item.action = importActionFail
+		case existing != nil && len(item.changes) == 0:
+			item.action = importActionUnchanged
+			item.reason = "unchanged"
+		case existing != nil:
+			item.action = importActionUpdate
+		}
+		plan = append(plan, item)
+	}
+
+	if err := uc.checkImportAddresses(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// checkImportAddresses fails the planned rows using an address of another server,
+// or of an earlier row in the file
+func (uc *ServerUsecase) checkImportAddresses(ctx context.Context, plan []importPlanItem) error {
+	var interfaces []entity.NetworkInterface
+	for _, item := range plan {
+		if item.action != importActionFail && item.action != importActionSkip {
+			interfaces = append(interfaces, item.interfaces...)
+		}
+	}
+	if len(interfaces) == 0 {
+		return nil
+	}
+
+	ipv4s, ipv6s, macs := interfaceAddresses(interfaces)
+	existing, err := uc.serverRepo.FindInterfaces(ctx, ipv4s, ipv6s, macs)
+	if err != nil {
+		return fmt.Errorf("failed to look up network addresses: %w", err)
+	}
+	owners := addressOwners(existing)
+
+	seen := make(map[string]int) // address -> line of the first row using it
+	for i := range plan {
+		item := &plan[i]
+		if item.action == importActionFail || item.action == importActionSkip {
+			continue
+		}
+		for _, iface := range item.interfaces {
+			for _, address := range []string{iface.IPv4, iface.IPv6, iface.MAC} {
+				if address == "" {
+					continue
+				}
+				if owner, ok := owners[address]; ok && owner != item.req.ID {
+					item.addError(importAddressColumn(item.req), fmt.Sprintf("address %s is already used by server %s", address, owner))
+				} else if line, ok := seen[address]; ok {
+					item.addError(importAddressColumn(item.req), fmt.Sprintf("address %s also used on line %d", address, line))
+				} else {
+					seen[address] = item.line
+				}
+			}
+		}
+		if len(item.errors) > 0 {
+			item.action = importActionFail
+		}
+	}
+	return nil
+}
+
+// importAddressColumn returns the column holding the addresses of req
+func importAddressColumn(req CreateServerRequest) string {
+	switch {
+	case req.Interfaces != nil:
+		return importColumnInterfaces
+	case req.IPv4 == "" && req.IPv6 != "":
+		return importColumnIPv6
+	default:
+		return importColumnIPv4
+	}
+}
+
+// checkImportUpdate records why item cannot update existing, following UpdateServer rules
+func (uc *ServerUsecase) checkImportUpdate(item *importPlanItem, existing *entity.Server) {
+	req := item.req
+	if req.Provider != "" && uc.providers.Resolve(req.Provider) != existing.Provider {
+		item.addError(importColumnProvider, fmt.Sprintf("provider cannot be changed from %s to %s", existing.Provider, uc.providers.Resolve(req.Provider)))
+	}
+	if req.Status != "" && req.Status != existing.Status && !uc.providers.IsManaged(existing.Provider) {
+		item.addError(importColumnStatus, fmt.Sprintf("cannot change status: %v", ErrUnmanagedServer))
+	}
+}
+
+// importChanges lists the fields row req changes on existing. Empty fields keep their value.
+func importChanges(existing *entity.Server, req CreateServerRequest) []string {
+	var changes []string
+	if req.Name != "" && req.Name != existing.Name {
+		changes = append(changes, fmt.Sprintf("name: %s -> %s", existing.Name, req.Name))
+	}
+	if req.IPv4 != "" && req.IPv4 != existing.IPv4 {
+		changes = append(changes, fmt.Sprintf("ipv4: %s -> %s", existing.IPv4, req.IPv4))
+	}
+	if req.IPv6 != "" && !sameAddress(req.IPv6, existing.IPv6) {
+		changes = append(changes, fmt.Sprintf("ipv6: %s -> %s", existing.IPv6, req.IPv6))
+	}
+	if req.Interfaces != nil && req.Interfaces.String() != existing.Interfaces.String() {
+		changes = append(changes, fmt.Sprintf("interfaces: %s -> %s", existing.Interfaces, req.Interfaces))
+	}
+	if req.Status != "" && req.Status != existing.Status {
+		changes = append(changes, fmt.Sprintf("status: %s -> %s", existing.Status, req.Status))
+	}
+	if req.Description != "" && req.Description != existing.Description {
+		changes = append(changes, fmt.Sprintf("description: %q -> %q", existing.Description, req.Description))
+	}
+	if req.Environment != "" && req.Environment != existing.Environment {
+		changes = append(changes, fmt.Sprintf("environment: %s -> %s", existing.Environment, req.Environment))
+	}
+	if req.Location != "" && req.Location != existing.Location {
+		changes = append(changes, fmt.Sprintf("location: %s -> %s", existing.Location, req.Location))
+	}
+	if req.Owner != "" && req.Owner != existing.Owner {
+		changes = append(changes, fmt.Sprintf("owner: %s -> %s", existing.Owner, req.Owner))
+	}
+	if req.Labels != nil && !req.Labels.Equal(existing.Labels) {
+		changes = append(changes, fmt.Sprintf("labels: %s -> %s", existing.Labels, req.Labels))
+	}
+	return changes
+}
+
+// optionalString returns nil for an empty import cell, which keeps the current value
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// importTestRepository keeps servers in memory, enough for imports to plan and apply
+type importTestRepository struct {
+	Repository
+	servers []entity.Server
+}
+
+func (r *importTestRepository) ListFields(ctx context.Context, filter ServerFilter, sort ServerSort, pagination ServerPagination, fields []string) (*[]entity.Server, int, error) {
+	from, to := min(pagination.From, len(r.servers)), len(r.servers)
+	if pagination.To > 0 {
+		to = min(pagination.To, len(r.servers))
+	}
+	servers := append([]entity.Server(nil), r.servers[from:max(from, to)]...)
+	return &servers, len(r.servers), nil
+}
+
+func (r *importTestRepository) List(ctx context.Context, filter ServerFilter, sort ServerSort, pagination ServerPagination) (*[]entity.Server, int, error) {
+	return r.ListFields(ctx, filter, sort, pagination, nil)
+}
+
+func (r *importTestRepository) FindByIDsOrNames(ctx context.Context, ids []string, names []string) ([]entity.Server, error) {
+	var found []entity.Server
+	for _, server := range r.servers {
+		if containsString(ids, server.ID) || containsString(names, server.Name) {
+			found = append(found, server)
+		}
+	}
+	return found, nil
+}
+
+func (r *importTestRepository) FindInterfaces(ctx context.Context, ipv4s []string, ipv6s []string, macs []string) ([]entity.NetworkInterface, error) {
+	return nil, nil
+}
+
+func (r *importTestRepository) GetByID(ctx context.Context, id string) (*entity.Server, error) {
+	for _, server := range r.servers {
+		if server.ID == id {
+			return &server, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *importTestRepository) CreateBatch(ctx context.Context, servers []entity.Server, batchSize int) error {
+	r.servers = append(r.servers, servers...)
+	return nil
+}
+
+func (r *importTestRepository) Update(ctx context.Context, server *entity.Server) error {
+	for i := range r.servers {
+		if r.servers[i].ID == server.ID {
+			r.servers[i] = *server
+		}
+	}
+	return nil
+}
+
+func (r *importTestRepository) Delete(ctx context.Context, id string) error {
+	for i := range r.servers {
+		if r.servers[i].ID == id {
+			r.servers = append(r.servers[:i], r.servers[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// importTestProvider fails to create the servers listed in failCreate
+type importTestProvider struct {
+	failCreate map[string]bool
+}
+
+func (p importTestProvider) CreateServer(ctx context.Context, server *entity.Server) error {
+	if p.failCreate[server.ID] {
+		return errors.New("no capacity")
+	}
+	return nil
+}
+func (importTestProvider) DeleteServer(ctx context.Context, id string) error        { return nil }
+func (importTestProvider) UpdateServer(ctx context.Context, s *entity.Server) error { return nil }
+func (importTestProvider) StartServer(ctx context.Context, id string) error         { return nil }
+func (importTestProvider) StopServer(ctx context.Context, id string) error          { return nil }
+func (importTestProvider) GetServerStatus(ctx context.Context, id string) (entity.ServerStatus, error) {
+	return entity.StatusOffline, nil
+}
+
+func TestImportReplaceKeepsMissingServersWhenApplyFails(t *testing.T) {
+	repo := &importTestRepository{servers: []entity.Server{
+		{ID: "web-1", Name: "web-1", IPv4: "10.0.0.1", Status: entity.StatusOffline, Provider: ProviderPort},
+		{ID: "old-1", Name: "old-1", IPv4: "10.0.0.9", Status: entity.StatusOffline, Provider: ProviderPort},
+	}}
+	providers := NewProviderRegistry(ProviderPort)
+	providers.Register(ProviderPort, importTestProvider{failCreate: map[string]bool{"web-2": true}})
+	uc := NewServerUsecase(repo, providers)
+
+	file := "id,name,ipv4\nweb-1,web-1,10.0.0.1\nweb-2,web-2,10.0.0.2\n"
+	result, err := uc.ImportServers(context.Background(), strings.NewReader(file), ImportCSV, ImportOptions{Mode: ImportReplace})
+	if err != nil {
+		t.Fatalf("ImportServers() unexpected error: %v", err)
+	}
+
+	if result.FailureCount != 1 {
+		t.Errorf("FailureCount = %d, want 1 (%v)", result.FailureCount, result.FailureServers)
+	}
+	if result.DeletedCount != 0 || len(result.DeletedServers) != 0 {
+		t.Errorf("deleted %v, want nothing deleted after a row failed", result.DeletedServers)
+	}
+	if server, _ := repo.GetByID(context.Background(), "old-1"); server == nil {
+		t.Errorf("old-1 was deleted, want it kept")
+	}
+	if result.SkippedCount != 1 || !strings.HasPrefix(result.SkippedServers[0], "old-1:old-1") {
+		t.Errorf("skipped %v, want old-1 reported as not deleted", result.SkippedServers)
+	}
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// ErrImportJobNotFound is returned when an import job does not exist
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// ErrImportJobFinished is returned when cancelling a job that has already stopped
+var ErrImportJobFinished = errors.New("import job already finished")
+
+// ErrImportQueueFull is returned when too many import jobs are waiting
+var ErrImportQueueFull = errors.New("import queue is full")
+
+// ImportJobService runs server imports in the background
+type ImportJobService interface {
+	SubmitImport(ctx context.Context, filename string, format ImportFormat, opts ImportOptions, data []byte) (*entity.ImportJob, error)
+	GetImportJob(ctx context.Context, jobID string) (*entity.ImportJob, error)
+	CancelImportJob(ctx context.Context, jobID string) (*entity.ImportJob, error)
+	GetImportJobReport(ctx context.Context, jobID string) ([]byte, error)
+}
+
+// importProgressInterval is how often the progress of a running job is saved
+const importProgressInterval = time.Second
+
+// importRequeueInterval is how often pending jobs are loaded again from the store once the
+// queue has drained, so jobs that did not fit in it are not left waiting
+const importRequeueInterval = 10 * time.Second
+
+// ImportJobQueue persists import jobs and processes them with a fixed number of workers
+type ImportJobQueue struct {
+	uc      *ServerUsecase
+	jobs    ImportJobRepository
+	workers int
+	queue   chan string
+
+	mu      sync.Mutex
+	running map[string]*runningImport
+}
+
+// runningImport is a job claimed by a worker
+type runningImport struct {
+	cancel    context.CancelFunc
+	cancelled bool // cancelled by the user rather than by shutdown
+}
+
+// NewImportJobQueue creates a queue processing up to workers jobs at a time,
+// with up to queueSize jobs waiting
+func NewImportJobQueue(uc *ServerUsecase, jobs ImportJobRepository, workers int, queueSize int) *ImportJobQueue {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	return &ImportJobQueue{
+		uc:      uc,
+		jobs:    jobs,
+		workers: workers,
+		queue:   make(chan string, queueSize),
+		running: make(map[string]*runningImport),
+	}
+}
+
+// SubmitImport stores the uploaded file as a pending job and queues it
+func (q *ImportJobQueue) SubmitImport(ctx context.Context, filename string, format ImportFormat, opts ImportOptions, data []byte) (*entity.ImportJob, error) {
+	mode, err := ParseImportMode(string(opts.Mode))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := NewImporter(format); err != nil {
+		return nil, err
+	}
+
+	id, err := newImportJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &entity.ImportJob{
+		ID:        id,
+		Status:    entity.ImportJobPending,
+		Filename:  filename,
+		Format:    string(format),
+		Mode:      string(mode),
+		DryRun:    opts.DryRun,
+		Atomic:    opts.Atomic,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+	if err := q.jobs.CreateJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	select {
+	case q.queue <- job.ID:
+	default:
+		job.Status = entity.ImportJobFailed
+		job.Error = ErrImportQueueFull.Error()
+		job.Data = nil
+		_ = q.jobs.UpdateJob(ctx, job)
+		return nil, ErrImportQueueFull
+	}
+
+	return job, nil
+}
+
+// GetImportJob returns a job and its progress
+func (q *ImportJobQueue) GetImportJob(ctx context.Context, jobID string) (*entity.ImportJob, error) {
+	job, err := q.jobs.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("%w: %s", ErrImportJobNotFound, jobID)
+	}
+	return job, nil
+}
+
+// GetImportJobReport returns the annotated XLSX report of a finished job
+func (q *ImportJobQueue) GetImportJobReport(ctx context.Context, jobID string) ([]byte, error) {
+	report, err := q.jobs.GetJobReport(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job report: %w", err)
+	}
+	if report == nil {
+		return nil, fmt.Errorf("%w: no report for %s", ErrImportJobNotFound, jobID)
+	}
+	return report, nil
+}
+
+// CancelImportJob stops a running job between rows, or drops a pending one.
+// Rows imported before cancellation are kept, unless the import is atomic.
+func (q *ImportJobQueue) CancelImportJob(ctx context.Context, jobID string) (*entity.ImportJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, err := q.GetImportJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if run, exists := q.running[jobID]; exists {
+		run.cancelled = true
+		run.cancel()
+		return job, nil // The worker records the cancellation once the current row is done
+	}
+
+	if job.Finished() {
+		return nil, fmt.Errorf("%w: %s is %s", ErrImportJobFinished, jobID, job.Status)
+	}
+
+	now := time.Now()
+	job.Status = entity.ImportJobCancelled
+	job.FinishedAt = &now
+	job.Data = nil
+	if err := q.jobs.UpdateJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to cancel import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// Run processes queued jobs until ctx is cancelled. Pending jobs left by a previous run are
+// queued again; jobs that were running when the application stopped are marked failed,
+// since some of their rows may already have been imported.
+func (q *ImportJobQueue) Run(ctx context.Context) {
+	q.failInterrupted(ctx)
+	q.queuePending(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(importRequeueInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if len(q.queue) == 0 {
+					q.queuePending(ctx)
+				}
+			}
+		}
+	}()
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case jobID := <-q.queue:
+					q.process(ctx, jobID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Helper methods
+
+// failInterrupted marks the jobs left running by a previous run as failed
+func (q *ImportJobQueue) failInterrupted(ctx context.Context) {
+	interrupted, err := q.jobs.ListJobsByStatus(ctx, entity.ImportJobRunning)
+	if err != nil {
+		return
+	}
+	for i := range interrupted {
+		q.finish(&interrupted[i], nil, errors.New("interrupted by application restart"))
+	}
+}
+
+// queuePending queues the pending jobs of the store, oldest first, as far as the queue has room.
+// A job queued twice is only processed once, since claim skips jobs that are no longer pending.
+func (q *ImportJobQueue) queuePending(ctx context.Context) {
+	pending, err := q.jobs.ListJobsByStatus(ctx, entity.ImportJobPending)
+	if err != nil {
+		return
+	}
+	for _, job := range pending {
+		select {
+		case q.queue <- job.ID:
+		default:
+			return // The rest is queued once the queue has drained
+		}
+	}
+}
+
+// claim marks a pending job as running and registers its cancel function
+func (q *ImportJobQueue) claim(ctx context.Context, jobID string) (*entity.ImportJob, context.Context, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, err := q.jobs.GetJob(ctx, jobID)
+	if err != nil || job == nil || job.Status != entity.ImportJobPending {
+		return nil, nil, err // Cancelled while waiting
+	}
+	// The file is loaded before the job is saved again, which would otherwise clear it
+	if job.Data, err = q.jobs.GetJobData(ctx, jobID); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	job.Status = entity.ImportJobRunning
+	job.StartedAt = &now
+	if err := q.jobs.UpdateJob(ctx, job); err != nil {
+		return nil, nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	q.running[jobID] = &runningImport{cancel: cancel}
+	return job, jobCtx, nil
+}
+
+func (q *ImportJobQueue) process(ctx context.Context, jobID string) {
+	job, jobCtx, err := q.claim(ctx, jobID)
+	if err != nil || job == nil {
+		return
+	}
+
+	importer, err := NewImporter(ImportFormat(job.Format))
+	if err != nil {
+		q.finish(job, nil, err)
+		return
+	}
+	sheet, err := importer.ReadSheet(bytes.NewReader(job.Data))
+	if err != nil {
+		q.finish(job, nil, err)
+		return
+	}
+
+	job.Data = nil // Interrupted jobs are not resumed, so the file is no longer needed
+	job.TotalRows = len(sheet.Rows)
+	if err := q.jobs.UpdateJob(ctx, job); err != nil {
+		q.finish(job, nil, err)
+		return
+	}
+
+	lastSaved := time.Now()
+	opts := ImportOptions{Mode: ImportMode(job.Mode), DryRun: job.DryRun, Atomic: job.Atomic, Report: true}
+	result, err := q.uc.importSheet(jobCtx, sheet, opts, func(result *ImportRespose) {
+		job.ProcessedRows = result.SuccessCount + result.FailureCount + result.SkippedCount
+		job.SucceededRows = result.SuccessCount
+		job.FailedRows = result.FailureCount
+		if time.Since(lastSaved) >= importProgressInterval {
+			lastSaved = time.Now()
+			_ = q.jobs.UpdateJob(ctx, job)
+		}
+	})
+	q.finish(job, result, err)
+}
+
+// finish records the outcome of a job. It uses its own context so the
+// outcome is saved even when the job was stopped by shutdown.
+func (q *ImportJobQueue) finish(job *entity.ImportJob, result *ImportRespose, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	run := q.running[job.ID]
+	if run != nil {
+		run.cancel()
+		delete(q.running, job.ID)
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Data = nil
+
+	switch {
+	case err == nil:
+		job.Status = entity.ImportJobCompleted
+	case run != nil && run.cancelled:
+		job.Status = entity.ImportJobCancelled
+	case errors.Is(err, context.Canceled):
+		job.Status = entity.ImportJobFailed
+		job.Error = "interrupted by application shutdown"
+	default:
+		job.Status = entity.ImportJobFailed
+		job.Error = err.Error()
+	}
+
+	if result != nil {
+		job.ProcessedRows = result.SuccessCount + result.FailureCount + result.SkippedCount
+		job.SucceededRows = result.SuccessCount
+		job.FailedRows = result.FailureCount
+		if encoded, err := json.Marshal(result); err == nil {
+			job.Result = encoded
+		}
+		job.Report = result.Report
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = q.jobs.UpdateJob(ctx, job)
+}
+
+// newImportJobID returns a random 128-bit hex ID
+func newImportJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate import job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lits-06/vcs-sms/entity"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	importReportSheet   = "Import Report"
+	importTemplateSheet = "Servers"
+
+	// importTemplateRows is the number of rows covered by the template's data validation
+	importTemplateRows = 10000
+)
+
+// importReportFills maps row statuses to the background and font colors of their status cell
+var importReportFills = map[ImportRowStatus][2]string{
+	ImportRowCreated:   {"C6EFCE", "006100"},
+	ImportRowUpdated:   {"C6EFCE", "006100"},
+	ImportRowUnchanged: {"EDEDED", "404040"},
+	ImportRowSkipped:   {"FFEB9C", "9C5700"},
+	ImportRowFailed:    {"FFC7CE", "9C0006"},
+}
+
+// writeImportReport writes sheet back as XLSX with an Import Status and an Import Error column.
+// Rows keep their position in the uploaded file and cells holding invalid values are highlighted,
+// so the report can be fixed and uploaded again as is.
+func writeImportReport(w io.Writer, sheet *ImportSheet, result *ImportRespose) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", importReportSheet); err != nil {
+		return fmt.Errorf("failed to name report sheet: %w", err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %w", err)
+	}
+	invalidStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Color: importReportFills[ImportRowFailed][1]},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{importReportFills[ImportRowFailed][0]}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create highlight style: %w", err)
+	}
+	statusStyles := make(map[ImportRowStatus]int, len(importReportFills))
+	for status, colors := range importReportFills {
+		style, err := f.NewStyle(&excelize.Style{
+			Font: &excelize.Font{Bold: true, Color: colors[1]},
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{colors[0]}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create status style: %w", err)
+		}
+		statusStyles[status] = style
+	}
+
+	sw, err := f.NewStreamWriter(importReportSheet)
+	if err != nil {
+		return fmt.Errorf("failed to create stream writer: %w", err)
+	}
+
+	width := len(sheet.Headers)
+	if err := sw.SetColWidth(1, width, 18); err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(width+2, width+2, 60); err != nil {
+		return err
+	}
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	headers := make([]interface{}, 0, width+2)
+	for _, header := range sheet.Headers {
+		headers = append(headers, excelize.Cell{StyleID: headerStyle, Value: header})
+	}
+	headers = append(headers,
+		excelize.Cell{StyleID: headerStyle, Value: "Import Status"},
+		excelize.Cell{StyleID: headerStyle, Value: "Import Error"},
+	)
+	if err := sw.SetRow("A1", headers); err != nil {
+		return fmt.Errorf("failed to write header row: %w", err)
+	}
+
+	columns := make([]string, width)
+	for i, header := range sheet.Headers {
+		columns[i] = importColumn(header)
+	}
+
+	for _, row := range sheet.Rows {
+		outcome := result.Outcomes[row.Line]
+		invalid := make(map[string]bool, len(outcome.Columns))
+		for _, column := range outcome.Columns {
+			invalid[column] = true
+		}
+
+		values := make([]interface{}, 0, width+2)
+		for i, value := range row.Cells {
+			if columns[i] != "" && invalid[columns[i]] {
+				values = append(values, excelize.Cell{StyleID: invalidStyle, Value: value})
+				continue
+			}
+			values = append(values, value)
+		}
+		values = append(values,
+			excelize.Cell{StyleID: statusStyles[outcome.Status], Value: string(outcome.Status)},
+			outcome.Message,
+		)
+
+		cell, _ := excelize.CoordinatesToCellName(1, row.Line+sheet.rowOffset)
+		if err := sw.SetRow(cell, values); err != nil {
+			return fmt.Errorf("failed to write row %d: %w", row.Line, err)
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush report rows: %w", err)
+	}
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// ImportTemplate writes a blank XLSX import file with the expected headers.
+// Data validation offers the allowed statuses and providers and rejects malformed values.
+func (uc *ServerUsecase) ImportTemplate(ctx context.Context, w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", importTemplateSheet); err != nil {
+		return fmt.Errorf("failed to name template sheet: %w", err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %w", err)
+	}
+
+	headers := []string{"ID", "Name", "IPv4", "Status", "Provider", "Description", "Environment", "Location", "Owner", "Labels", "IPv6", "Interfaces"}
+	if err := f.SetSheetRow(importTemplateSheet, "A1", &headers); err != nil {
+		return fmt.Errorf("failed to write header row: %w", err)
+	}
+	if err := f.SetCellStyle(importTemplateSheet, "A1", "L1", headerStyle); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(importTemplateSheet, "A", "I", 20); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(importTemplateSheet, "J", "J", 40); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(importTemplateSheet, "K", "K", 24); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(importTemplateSheet, "L", "L", 50); err != nil {
+		return err
+	}
+	if err := f.SetPanes(importTemplateSheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	lastRow := importTemplateRows + 1
+	validations := []func() (*excelize.DataValidation, error){
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("A2:B%d", lastRow))
+			dv.SetInput("Required", "ID and name must be unique")
+			dv.SetError(excelize.DataValidationErrorStyleStop, "Invalid value", "Enter between 1 and 255 characters")
+			return dv, dv.SetRange(1, 255, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween)
+		},
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("C2:C%d", lastRow))
+			dv.SetInput("Address", "IPv4 address, such as 10.0.0.1. Each server needs an IPv4, IPv6 or Interfaces value")
+			dv.SetError(excelize.DataValidationErrorStyleStop, "Invalid IPv4 address", "Enter an IPv4 address such as 10.0.0.1")
+			return dv, dv.SetRange(7, 15, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween)
+		},
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("D2:D%d", lastRow))
+			dv.SetInput("Optional", "ON or OFF, empty means OFF")
+			dv.SetError(excelize.DataValidationErrorStyleStop, "Invalid status", "Status must be ON or OFF")
+			return dv, dv.SetDropList([]string{string(entity.StatusOnline), string(entity.StatusOffline)})
+		},
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("E2:E%d", lastRow))
+			dv.SetInput("Optional", "Empty uses the default provider")
+			dv.SetError(excelize.DataValidationErrorStyleStop, "Invalid provider", "Provider must be one of "+strings.Join(uc.providers.Names(), ", "))
+			return dv, dv.SetDropList(uc.providers.Names())
+		},
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("G2:I%d", lastRow))
+			dv.SetInput("Optional", "Up to 255 characters, empty keeps the current value")
+			dv.SetError(excelize.DataValidationErrorStyleStop, "Invalid value", "Enter at most 255 characters")
+			return dv, dv.SetRange(0, 255, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween)
+		},
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("J2:J%d", lastRow))
+			dv.SetInput("Optional", "key=value pairs separated by commas, such as region=us-east,tier=web")
+			return dv, nil
+		},
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("K2:K%d", lastRow))
+			dv.SetInput("Address", "IPv6 address, such as fd00::1")
+			dv.SetError(excelize.DataValidationErrorStyleStop, "Invalid IPv6 address", "Enter an IPv6 address such as fd00::1")
+			return dv, dv.SetRange(2, 39, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween)
+		},
+		func() (*excelize.DataValidation, error) {
+			dv := excelize.NewDataValidation(true)
+			dv.SetSqref(fmt.Sprintf("L2:L%d", lastRow))
+			dv.SetInput("Optional", "name=address|address separated by semicolons, primary first, such as eth0=10.0.0.5|fd00::5; mgmt=10.1.0.5")
+			return dv, nil
+		},
+	}
+	for _, validation := range validations {
+		dv, err := validation()
+		if err != nil {
+			return fmt.Errorf("failed to create data validation: %w", err)
+		}
+		if err := f.AddDataValidation(importTemplateSheet, dv); err != nil {
+			return fmt.Errorf("failed to add data validation: %w", err)
+		}
+	}
+
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// resolveInterfaces returns the validated interfaces of a server described by a create request.
+// Without interfaces, ipv4 and ipv6 make up a single primary eth0 interface; with them,
+// ipv4 and ipv6 must match the primary interface when they are set.
+func resolveInterfaces(ipv4, ipv6 string, interfaces entity.NetworkInterfaces) (entity.NetworkInterfaces, error) {
+	if len(interfaces) == 0 {
+		if ipv4 == "" && ipv6 == "" {
+			return nil, fmt.Errorf("an IPv4 or IPv6 address is required")
+		}
+		interfaces = entity.NetworkInterfaces{{Name: entity.DefaultInterfaceName, IPv4: ipv4, IPv6: ipv6, Primary: true}}
+	} else {
+		interfaces = slices.Clone(interfaces)
+	}
+
+	if err := interfaces.Normalize(); err != nil {
+		return nil, err
+	}
+
+	primary, _ := interfaces.Primary()
+	if ipv4 != "" && !sameAddress(ipv4, primary.IPv4) {
+		return nil, fmt.Errorf("IPv4 %s does not match primary interface %s", ipv4, primary.Name)
+	}
+	if ipv6 != "" && !sameAddress(ipv6, primary.IPv6) {
+		return nil, fmt.Errorf("IPv6 %s does not match primary interface %s", ipv6, primary.Name)
+	}
+	if primary.IPv4 == "" && primary.IPv6 == "" {
+		return nil, fmt.Errorf("primary interface %s needs an IPv4 or IPv6 address", primary.Name)
+	}
+	return interfaces, nil
+}
+
+// updateInterfaces returns the interfaces of server after applying req
+func updateInterfaces(server *entity.Server, req UpdateServerRequest) (entity.NetworkInterfaces, error) {
+	if req.Interfaces != nil {
+		return resolveInterfaces(req.IPv4, req.IPv6, req.Interfaces)
+	}
+
+	interfaces := slices.Clone(server.Interfaces)
+	if len(interfaces) == 0 {
+		interfaces = entity.NetworkInterfaces{{Name: entity.DefaultInterfaceName, IPv4: server.IPv4, IPv6: server.IPv6, Primary: true}}
+	}
+	for i := range interfaces {
+		if !interfaces[i].Primary {
+			continue
+		}
+		if req.IPv4 != "" {
+			interfaces[i].IPv4 = req.IPv4
+		}
+		if req.IPv6 != "" {
+			interfaces[i].IPv6 = req.IPv6
+		}
+	}
+	return resolveInterfaces("", "", interfaces)
+}
+
+// sameAddress reports whether a and b are the same IP address, whatever their notation
+func sameAddress(a, b string) bool {
+	addrA, errA := netip.ParseAddr(strings.TrimSpace(a))
+	addrB, errB := netip.ParseAddr(b)
+	return errA == nil && errB == nil && addrA == addrB
+}
+
+// interfaceAddresses splits the addresses of interfaces by kind
+func interfaceAddresses(interfaces []entity.NetworkInterface) (ipv4s, ipv6s, macs []string) {
+	for _, iface := range interfaces {
+		if iface.IPv4 != "" {
+			ipv4s = append(ipv4s, iface.IPv4)
+		}
+		if iface.IPv6 != "" {
+			ipv6s = append(ipv6s, iface.IPv6)
+		}
+		if iface.MAC != "" {
+			macs = append(macs, iface.MAC)
+		}
+	}
+	return ipv4s, ipv6s, macs
+}
+
+// addressOwners maps every IPv4, IPv6 and MAC address of interfaces to the ID of its server
+func addressOwners(interfaces []entity.NetworkInterface) map[string]string {
+	owners := make(map[string]string, len(interfaces))
+	for _, iface := range interfaces {
+		for _, address := range []string{iface.IPv4, iface.IPv6, iface.MAC} {
+			if address != "" {
+				owners[address] = iface.ServerID
+			}
+		}
+	}
+	return owners
+}
+
+// checkAddresses fails when an address of interfaces belongs to a server other than serverID
+func (uc *ServerUsecase) checkAddresses(ctx context.Context, serverID string, interfaces entity.NetworkInterfaces) error {
+	ipv4s, ipv6s, macs := interfaceAddresses(interfaces)
+	existing, err := uc.serverRepo.FindInterfaces(ctx, ipv4s, ipv6s, macs)
+	if err != nil {
+		return fmt.Errorf("failed to check network addresses: %w", err)
+	}
+
+	owners := addressOwners(existing)
+	for _, iface := range interfaces {
+		for _, address := range []string{iface.IPv4, iface.IPv6, iface.MAC} {
+			if owner, ok := owners[address]; ok && address != "" && owner != serverID {
+				return fmt.Errorf("address %s is already used by server %s", address, owner)
+			}
+		}
+	}
+	return nil
+}
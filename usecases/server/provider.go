@@ -2,6 +2,10 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/lits-06/vcs-sms/entity"
 )
@@ -14,3 +18,112 @@ type Provider interface {
 	StopServer(ctx context.Context, serverID string) error
 	GetServerStatus(ctx context.Context, serverID string) (entity.ServerStatus, error)
 }
+
+// Shutdowner is implemented by providers that own running servers and must
+// release them when the application exits. When stopServers is false the
+// provider detaches from servers that can outlive the application instead of stopping them.
+type Shutdowner interface {
+	Shutdown(ctx context.Context, stopServers bool) error
+}
+
+// UptimeReporter is implemented by providers that know when their running servers were started
+type UptimeReporter interface {
+	// StartedAt returns when the server was last started, or the zero time when it is not running
+	StartedAt(ctx context.Context, serverID string) (time.Time, error)
+}
+
+// Shutdown policies
+const (
+	ShutdownPolicyStop   = "stop"
+	ShutdownPolicyDetach = "detach"
+)
+
+// Provider names
+const (
+	ProviderPort     = "port"     // simulated servers listening on local ports
+	ProviderProcess  = "process"  // servers running as local OS processes
+	ProviderExternal = "external" // servers managed outside this system, health-checked only
+)
+
+// ErrUnmanagedServer is returned when trying to start or stop a server
+// whose lifecycle is not managed by this system
+var ErrUnmanagedServer = errors.New("server is not managed by this system")
+
+// ProviderRegistry maps provider names to their implementations
+type ProviderRegistry struct {
+	providers       map[string]Provider
+	unmanaged       map[string]bool
+	defaultProvider string
+}
+
+// NewProviderRegistry creates an empty registry. Servers created without an
+// explicit provider are assigned to defaultProvider, which falls back to ProviderPort.
+func NewProviderRegistry(defaultProvider string) *ProviderRegistry {
+	if defaultProvider == "" {
+		defaultProvider = ProviderPort
+	}
+
+	return &ProviderRegistry{
+		providers:       make(map[string]Provider),
+		unmanaged:       make(map[string]bool),
+		defaultProvider: defaultProvider,
+	}
+}
+
+// Register adds a provider whose servers are started and stopped by us
+func (r *ProviderRegistry) Register(name string, provider Provider) {
+	r.providers[name] = provider
+}
+
+// RegisterUnmanaged adds a provider whose servers are only health-checked
+func (r *ProviderRegistry) RegisterUnmanaged(name string, provider Provider) {
+	r.providers[name] = provider
+	r.unmanaged[name] = true
+}
+
+// Get returns the provider registered under name, or the default provider when name is empty
+func (r *ProviderRegistry) Get(name string) (Provider, error) {
+	provider, ok := r.providers[r.Resolve(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown server provider %q", name)
+	}
+	return provider, nil
+}
+
+// Resolve returns the effective provider name
+func (r *ProviderRegistry) Resolve(name string) string {
+	if name == "" {
+		return r.defaultProvider
+	}
+	return name
+}
+
+// IsManaged reports whether servers of the given provider can be started and stopped
+func (r *ProviderRegistry) IsManaged(name string) bool {
+	return !r.unmanaged[r.Resolve(name)]
+}
+
+// Names returns the registered provider names in sorted order
+func (r *ProviderRegistry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shutdown releases the servers of every registered provider according to policy
+func (r *ProviderRegistry) Shutdown(ctx context.Context, policy string) error {
+	var errs []error
+	for _, name := range r.Names() {
+		shutdowner, ok := r.providers[name].(Shutdowner)
+		if !ok {
+			continue
+		}
+		if err := shutdowner.Shutdown(ctx, policy != ShutdownPolicyDetach); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,505 @@
+package server
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// MaxQueryLength is the longest query expression accepted, in characters
+const MaxQueryLength = 2000
+
+// maxQueryDepth limits the nesting of parentheses and NOT
+const maxQueryDepth = 32
+
+// QueryError is a syntax or validation error in a query expression
+type QueryError struct {
+	Pos     int // 1-based character position of the offending token
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+}
+
+// QueryExpr is a node of a parsed query expression:
+// a *QueryAnd, *QueryOr, *QueryNot or *QueryComparison
+type QueryExpr interface {
+	queryExpr()
+}
+
+// QueryAnd matches servers matching every operand
+type QueryAnd struct {
+	Operands []QueryExpr
+}
+
+// QueryOr matches servers matching any operand
+type QueryOr struct {
+	Operands []QueryExpr
+}
+
+// QueryNot matches servers not matching its operand
+type QueryNot struct {
+	Operand QueryExpr
+}
+
+func (*QueryAnd) queryExpr()        {}
+func (*QueryOr) queryExpr()         {}
+func (*QueryNot) queryExpr()        {}
+func (*QueryComparison) queryExpr() {}
+
+// QueryOperator compares a field with a value
+type QueryOperator string
+
+const (
+	QueryMatch          QueryOperator = ":" // wildcard match for text, equality otherwise
+	QueryEquals         QueryOperator = "="
+	QueryNotEquals      QueryOperator = "!="
+	QueryGreater        QueryOperator = ">"
+	QueryGreaterOrEqual QueryOperator = ">="
+	QueryLess           QueryOperator = "<"
+	QueryLessOrEqual    QueryOperator = "<="
+)
+
+// QueryFieldKind decides which operators and values a field accepts
+type QueryFieldKind int
+
+const (
+	QueryText QueryFieldKind = iota
+	QueryStatus
+	QueryIPv4
+	QueryIPv6
+	QueryMAC
+	QueryTime
+	QueryLabel
+)
+
+// queryFields is the whitelist of fields a query may refer to.
+// Labels are queried as labels.<key>.
+var queryFields = map[string]QueryFieldKind{
+	"id":          QueryText,
+	"name":        QueryText,
+	"provider":    QueryText,
+	"description": QueryText,
+	"environment": QueryText,
+	"location":    QueryText,
+	"owner":       QueryText,
+	"status":      QueryStatus,
+	"ipv4":        QueryIPv4,
+	"ipv6":        QueryIPv6,
+	"mac":         QueryMAC,
+	"created_at":  QueryTime,
+	"updated_at":  QueryTime,
+}
+
+const queryLabelPrefix = "labels."
+
+// QueryComparison compares a whitelisted field with a value, which is parsed according to the field
+type QueryComparison struct {
+	Field    string // such as name, created_at or labels.env
+	Kind     QueryFieldKind
+	Operator QueryOperator
+	Value    string
+	Pos      int
+
+	Label    string         // key of a labels.<key> field
+	IPRanges []IPRange      // ipv4 values, see ParseIPFilter
+	Prefixes []netip.Prefix // ipv6 values, see ParseIPv6Filter
+	MACs     []string       // mac values, see ParseMACFilter
+	Time     time.Time      // created_at and updated_at values, the next day for a date after > or <=, which become >= and <
+	Until    time.Time      // end of the day when a date is matched with :, = or !=, zero otherwise
+}
+
+// Wildcard reports whether a text value holds a * or ? wildcard
+func (c *QueryComparison) Wildcard() bool {
+	return strings.ContainsAny(c.Value, "*?")
+}
+
+// Pattern returns the value as an ILIKE pattern: * matches any text and ? one character,
+// while % and _ are matched literally
+func (c *QueryComparison) Pattern() string {
+	var b strings.Builder
+	for _, r := range c.Value {
+		switch r {
+		case '\\', '%', '_':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '*':
+			b.WriteRune('%')
+		case '?':
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ParseQuery parses a query expression such as
+//
+//	status:OFF AND (name:db-* OR ipv4:10.1.0.0/16) AND created_at>2026-01-01
+//
+// Comparisons are field, operator and value; values holding spaces or parentheses are quoted
+// with double quotes. Terms are combined with AND, OR and NOT and grouped with parentheses;
+// AND binds tighter than OR and terms written side by side are ANDed.
+// Text fields matched with : are case-insensitive and accept * and ? wildcards.
+// Dates are YYYY-MM-DD in server local time or RFC 3339 timestamps. A date covers the whole day,
+// so created_at<=2026-01-31 includes January 31 and created_at>2026-01-01 starts on January 2. An empty expression returns nil.
+func ParseQuery(s string) (QueryExpr, error) {
+	input := []rune(s)
+	if len(input) > MaxQueryLength {
+		return nil, &QueryError{Pos: MaxQueryLength + 1, Message: fmt.Sprintf("query is longer than %d characters", MaxQueryLength)}
+	}
+
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == queryTokenEOF {
+		return nil, nil
+	}
+
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != queryTokenEOF {
+		return nil, &QueryError{Pos: token.pos, Message: fmt.Sprintf("unexpected %s", token)}
+	}
+	return expr, nil
+}
+
+type queryTokenKind int
+
+const (
+	queryTokenEOF queryTokenKind = iota
+	queryTokenLeftParen
+	queryTokenRightParen
+	queryTokenAnd
+	queryTokenOr
+	queryTokenNot
+	queryTokenComparison
+)
+
+type queryToken struct {
+	kind       queryTokenKind
+	pos        int
+	text       string
+	comparison *QueryComparison
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case queryTokenEOF:
+		return "end of query"
+	case queryTokenComparison:
+		return fmt.Sprintf("%q", t.comparison.Field+string(t.comparison.Operator)+t.comparison.Value)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// lexQuery splits the input into parentheses, keywords and comparisons
+func lexQuery(input []rune) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+	for {
+		for i < len(input) && unicode.IsSpace(input[i]) {
+			i++
+		}
+		if i >= len(input) {
+			return append(tokens, queryToken{kind: queryTokenEOF, pos: len(input) + 1}), nil
+		}
+
+		start := i
+		switch input[i] {
+		case '(':
+			tokens = append(tokens, queryToken{kind: queryTokenLeftParen, pos: start + 1, text: "("})
+			i++
+			continue
+		case ')':
+			tokens = append(tokens, queryToken{kind: queryTokenRightParen, pos: start + 1, text: ")"})
+			i++
+			continue
+		}
+
+		for i < len(input) && isQueryFieldRune(input[i]) {
+			i++
+		}
+		word := string(input[start:i])
+		if word == "" {
+			return nil, &QueryError{Pos: start + 1, Message: fmt.Sprintf("unexpected character %q", input[i])}
+		}
+
+		operator := queryOperatorAt(input, i)
+		if operator == "" {
+			switch strings.ToUpper(word) {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: queryTokenAnd, pos: start + 1, text: word})
+				continue
+			case "OR":
+				tokens = append(tokens, queryToken{kind: queryTokenOr, pos: start + 1, text: word})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: queryTokenNot, pos: start + 1, text: word})
+				continue
+			}
+			return nil, &QueryError{Pos: i + 1, Message: fmt.Sprintf("expected an operator (:, =, !=, >, >=, <, <=) after %q", word)}
+		}
+		i += len(operator)
+
+		valuePos := i + 1
+		value, next, err := lexQueryValue(input, i)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		comparison, err := newQueryComparison(word, start+1, QueryOperator(operator), value, valuePos)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, queryToken{kind: queryTokenComparison, pos: start + 1, comparison: comparison})
+	}
+}
+
+func isQueryFieldRune(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || r == '/' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// queryOperatorAt returns the comparison operator starting at input[i], or ""
+func queryOperatorAt(input []rune, i int) string {
+	rest := string(input[i:min(i+2, len(input))])
+	for _, operator := range []QueryOperator{QueryNotEquals, QueryGreaterOrEqual, QueryLessOrEqual, QueryMatch, QueryEquals, QueryGreater, QueryLess} {
+		if strings.HasPrefix(rest, string(operator)) {
+			return string(operator)
+		}
+	}
+	return ""
+}
+
+// lexQueryValue reads a bare value, which ends at a space or parenthesis, or a double-quoted
+// value in which \" and \\ are escapes. It returns the value and the index following it.
+func lexQueryValue(input []rune, i int) (string, int, error) {
+	if i < len(input) && input[i] == '"' {
+		start := i
+		var b strings.Builder
+		for i++; i < len(input); i++ {
+			switch input[i] {
+			case '\\':
+				if i+1 < len(input) {
+					i++
+					b.WriteRune(input[i])
+				}
+			case '"':
+				return b.String(), i + 1, nil
+			default:
+				b.WriteRune(input[i])
+			}
+		}
+		return "", 0, &QueryError{Pos: start + 1, Message: "unterminated quoted value"}
+	}
+
+	start := i
+	for i < len(input) && !unicode.IsSpace(input[i]) && input[i] != '(' && input[i] != ')' {
+		i++
+	}
+	if i == start {
+		return "", 0, &QueryError{Pos: start + 1, Message: "missing value"}
+	}
+	return string(input[start:i]), i, nil
+}
+
+// newQueryComparison checks field against the whitelist and parses value according to the field
+func newQueryComparison(field string, pos int, operator QueryOperator, value string, valuePos int) (*QueryComparison, error) {
+	c := &QueryComparison{Field: strings.ToLower(field), Operator: operator, Value: value, Pos: pos}
+
+	kind, ok := queryFields[c.Field]
+	if key, isLabel := strings.CutPrefix(field, queryLabelPrefix); isLabel {
+		if !entity.ValidLabelKey(key) {
+			return nil, &QueryError{Pos: pos, Message: fmt.Sprintf("invalid label key %q", key)}
+		}
+		c.Field, c.Label, kind, ok = field, key, QueryLabel, true
+	}
+	if !ok {
+		return nil, &QueryError{Pos: pos, Message: fmt.Sprintf("unknown field %q (use %s or labels.<key>)", field, strings.Join(queryFieldNames(), ", "))}
+	}
+	c.Kind = kind
+
+	if kind != QueryTime && operator != QueryMatch && operator != QueryEquals && operator != QueryNotEquals {
+		return nil, &QueryError{Pos: pos, Message: fmt.Sprintf("operator %s is not supported on %s (use :, = or !=)", operator, c.Field)}
+	}
+
+	invalid := func(err error) error {
+		return &QueryError{Pos: valuePos, Message: err.Error()}
+	}
+	switch kind {
+	case QueryStatus:
+		status := entity.ServerStatus(strings.ToUpper(value))
+		if status != entity.StatusOnline && status != entity.StatusOffline {
+			return nil, &QueryError{Pos: valuePos, Message: fmt.Sprintf("invalid status %q (use ON or OFF)", value)}
+		}
+		c.Value = string(status)
+	case QueryIPv4:
+		ranges, err := ParseIPFilter(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		c.IPRanges = ranges
+	case QueryIPv6:
+		prefixes, err := ParseIPv6Filter(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		c.Prefixes = prefixes
+	case QueryMAC:
+		macs, err := ParseMACFilter(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		c.MACs = macs
+	case QueryTime:
+		t, day, err := parseQueryTime(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		c.Time = t
+		if day {
+			// A date stands for the whole day, so the bounds after it start on the next day
+			switch operator {
+			case QueryMatch, QueryEquals, QueryNotEquals:
+				c.Until = t.AddDate(0, 0, 1)
+			case QueryGreater:
+				c.Operator, c.Time = QueryGreaterOrEqual, t.AddDate(0, 0, 1)
+			case QueryLessOrEqual:
+				c.Operator, c.Time = QueryLess, t.AddDate(0, 0, 1)
+			}
+		}
+	case QueryLabel:
+		if value != "*" && !entity.ValidLabelValue(value) {
+			return nil, &QueryError{Pos: valuePos, Message: fmt.Sprintf("invalid label value %q (use * to match any value)", value)}
+		}
+	}
+	return c, nil
+}
+
+// parseQueryTime parses a date in server local time or an RFC 3339 timestamp.
+// day reports whether a whole day was given.
+func parseQueryTime(value string) (t time.Time, day bool, err error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q (use YYYY-MM-DD or RFC 3339)", value)
+}
+
+func queryFieldNames() []string {
+	names := make([]string, 0, len(queryFields))
+	for name := range queryFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type queryParser struct {
+	tokens []queryToken
+	next   int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) take() queryToken {
+	token := p.tokens[p.next]
+	if token.kind != queryTokenEOF {
+		p.next++
+	}
+	return token
+}
+
+// parseOr parses terms separated by OR
+func (p *queryParser) parseOr(depth int) (QueryExpr, error) {
+	operands := []QueryExpr{}
+	for {
+		operand, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if p.peek().kind != queryTokenOr {
+			break
+		}
+		p.take()
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &QueryOr{Operands: operands}, nil
+}
+
+// parseAnd parses terms separated by AND or written side by side
+func (p *queryParser) parseAnd(depth int) (QueryExpr, error) {
+	operands := []QueryExpr{}
+	for {
+		operand, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+
+		switch p.peek().kind {
+		case queryTokenAnd:
+			p.take()
+			continue
+		case queryTokenNot, queryTokenLeftParen, queryTokenComparison:
+			continue
+		}
+		break
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &QueryAnd{Operands: operands}, nil
+}
+
+// parseUnary parses a comparison, a parenthesized expression or a negated term
+func (p *queryParser) parseUnary(depth int) (QueryExpr, error) {
+	token := p.take()
+	if depth > maxQueryDepth {
+		return nil, &QueryError{Pos: token.pos, Message: fmt.Sprintf("query is nested more than %d levels deep", maxQueryDepth)}
+	}
+
+	switch token.kind {
+	case queryTokenComparison:
+		return token.comparison, nil
+	case queryTokenNot:
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryNot{Operand: operand}, nil
+	case queryTokenLeftParen:
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.kind != queryTokenRightParen {
+			return nil, &QueryError{Pos: closing.pos, Message: fmt.Sprintf("expected \")\" to close \"(\" at position %d, found %s", token.pos, closing)}
+		}
+		return expr, nil
+	case queryTokenEOF:
+		return nil, &QueryError{Pos: token.pos, Message: "unexpected end of query, expected a term"}
+	default:
+		return nil, &QueryError{Pos: token.pos, Message: fmt.Sprintf("unexpected %s, expected a term", token)}
+	}
+}
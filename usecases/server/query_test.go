@@ -0,0 +1,142 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// formatQuery renders expr with explicit grouping, so tests can compare the parsed structure
+func formatQuery(expr QueryExpr) string {
+	join := func(operands []QueryExpr, separator string) string {
+		parts := make([]string, len(operands))
+		for i, operand := range operands {
+			parts[i] = formatQuery(operand)
+		}
+		return "(" + strings.Join(parts, separator) + ")"
+	}
+
+	switch expr := expr.(type) {
+	case nil:
+		return ""
+	case *QueryAnd:
+		return join(expr.Operands, " AND ")
+	case *QueryOr:
+		return join(expr.Operands, " OR ")
+	case *QueryNot:
+		return "NOT " + formatQuery(expr.Operand)
+	case *QueryComparison:
+		return expr.Field + string(expr.Operator) + "[" + expr.Value + "]"
+	default:
+		return "?"
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "empty", query: "  ", want: ""},
+		{name: "single comparison", query: "status:off", want: "status:[OFF]"},
+		{name: "and binds tighter than or", query: "name:a OR name:b AND status:ON", want: "(name:[a] OR (name:[b] AND status:[ON]))"},
+		{name: "or on the left", query: "name:a AND name:b OR status:ON", want: "((name:[a] AND name:[b]) OR status:[ON])"},
+		{name: "parentheses", query: "name:a AND (name:b OR status:ON)", want: "(name:[a] AND (name:[b] OR status:[ON]))"},
+		{name: "implicit and", query: "name:a status:ON ipv4:10.0.0.1", want: "(name:[a] AND status:[ON] AND ipv4:[10.0.0.1])"},
+		{name: "implicit and before not and group", query: "name:a NOT status:ON (owner:x OR owner:y)", want: "(name:[a] AND NOT status:[ON] AND (owner:[x] OR owner:[y]))"},
+		{name: "keywords are case insensitive", query: "name:a or not name:b", want: "(name:[a] OR NOT name:[b])"},
+		{name: "not binds to one term", query: "NOT name:a AND name:b", want: "(NOT name:[a] AND name:[b])"},
+		{name: "quoted value", query: `description:"web (primary)" AND name:x`, want: "(description:[web (primary)] AND name:[x])"},
+		{name: "quoted escapes", query: `description:"say \"hi\" \\ bye"`, want: `description:[say "hi" \ bye]`},
+		{name: "quoted keyword", query: `owner:"OR"`, want: "owner:[OR]"},
+		{name: "operators", query: "created_at>=2026-01-01 created_at<2026-02-01 name!=x", want: "(created_at>=[2026-01-01] AND created_at<[2026-02-01] AND name!=[x])"},
+		{name: "label", query: "labels.env=prod", want: "labels.env=[prod]"},
+		{name: "nested at max depth", query: strings.Repeat("(", maxQueryDepth) + "name:a" + strings.Repeat(")", maxQueryDepth), want: "name:[a]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) unexpected error: %v", tt.query, err)
+			}
+			if got := formatQuery(expr); got != tt.want {
+				t.Errorf("ParseQuery(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantPos int
+		message string
+	}{
+		{name: "unknown field", query: "status:ON AND color:red", wantPos: 15, message: "unknown field"},
+		{name: "missing operator", query: "name", wantPos: 5, message: "expected an operator"},
+		{name: "missing value", query: "name: AND", wantPos: 6, message: "missing value"},
+		{name: "unterminated quote", query: `name:a description:"open`, wantPos: 20, message: "unterminated quoted value"},
+		{name: "unclosed parenthesis", query: "(name:a OR name:b", wantPos: 18, message: `to close "(" at position 1`},
+		{name: "unexpected closing parenthesis", query: "name:a)", wantPos: 7, message: `unexpected ")"`},
+		{name: "trailing operator", query: "name:a AND", wantPos: 11, message: "unexpected end of query"},
+		{name: "leading operator", query: "OR name:a", wantPos: 1, message: `unexpected "OR"`},
+		{name: "unsupported operator", query: "name>a", wantPos: 1, message: "operator > is not supported"},
+		{name: "invalid status", query: "status:maybe", wantPos: 8, message: "invalid status"},
+		{name: "invalid time", query: "name:a created_at>yesterday", wantPos: 19, message: "invalid time"},
+		{name: "invalid label key", query: "labels.=x", wantPos: 1, message: "invalid label key"},
+		{name: "unexpected character", query: "name:a !", wantPos: 8, message: "unexpected character"},
+		{name: "too deep", query: strings.Repeat("(", maxQueryDepth+1) + "name:a" + strings.Repeat(")", maxQueryDepth+1), wantPos: maxQueryDepth + 2, message: "nested more than"},
+		{name: "too deep with not", query: strings.Repeat("NOT ", maxQueryDepth+1) + "name:a", wantPos: 4*(maxQueryDepth+1) + 1, message: "nested more than"},
+		{name: "too long", query: "name:" + strings.Repeat("a", MaxQueryLength), wantPos: MaxQueryLength + 1, message: "longer than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("ParseQuery(%q) error = %v, want a *QueryError", tt.query, err)
+			}
+			if queryErr.Pos != tt.wantPos || !strings.Contains(queryErr.Message, tt.message) {
+				t.Errorf("ParseQuery(%q) error = %q at %d, want %q at %d", tt.query, queryErr.Message, queryErr.Pos, tt.message, tt.wantPos)
+			}
+		})
+	}
+}
+
+func TestParseQueryDayRange(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	next := day.AddDate(0, 0, 1)
+	tests := []struct {
+		query    string
+		operator QueryOperator
+		time     time.Time
+		until    time.Time
+	}{
+		{"created_at:2026-01-01", QueryMatch, day, next},
+		{"created_at=2026-01-01", QueryEquals, day, next},
+		{"created_at!=2026-01-01", QueryNotEquals, day, next},
+		{"created_at>=2026-01-01", QueryGreaterOrEqual, day, time.Time{}},
+		{"created_at<2026-01-01", QueryLess, day, time.Time{}},
+		{"created_at>2026-01-01", QueryGreaterOrEqual, next, time.Time{}},
+		{"created_at<=2026-01-01", QueryLess, next, time.Time{}},
+		{"created_at=2026-01-01T00:00:00Z", QueryEquals, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		{"created_at<=2026-01-01T00:00:00Z", QueryLessOrEqual, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) unexpected error: %v", tt.query, err)
+		}
+		c := expr.(*QueryComparison)
+		if c.Operator != tt.operator || !c.Time.Equal(tt.time) || !c.Until.Equal(tt.until) {
+			t.Errorf("ParseQuery(%q) = %s %v until %v, want %s %v until %v",
+				tt.query, c.Operator, c.Time, c.Until, tt.operator, tt.time, tt.until)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"github.com/lits-06/vcs-sms/entity"
 )
@@ -17,8 +18,62 @@ type Repository interface {
 
 	// Query operations
 	List(ctx context.Context, filter ServerFilter, sort ServerSort, pagination ServerPagination) (*[]entity.Server, int, error)
+	// ListFields is List loading only the given fields of each server and its ID, or every field when fields is empty
+	ListFields(ctx context.Context, filter ServerFilter, sort ServerSort, pagination ServerPagination, fields []string) (*[]entity.Server, int, error)
+	// Search returns up to limit servers matching query, best matches first, without highlights
+	Search(ctx context.Context, query string, limit int) ([]ServerSearchResult, error)
+	// Stats aggregates the servers matching filter, with creation histograms in the time zone loc
+	Stats(ctx context.Context, filter ServerFilter, loc *time.Location) (*ServerStats, error)
 
 	// Validation operations
 	ExistsWithID(ctx context.Context, id string) (bool, error)
 	ExistsWithName(ctx context.Context, name string) (bool, error)
+
+	// Batch operations
+	CreateBatch(ctx context.Context, servers []entity.Server, batchSize int) error
+	// FindByIDsOrNames returns the servers whose ID is in ids or whose name is in names
+	FindByIDsOrNames(ctx context.Context, ids []string, names []string) ([]entity.Server, error)
+	// FindInterfaces returns the network interfaces holding any of the given addresses
+	FindInterfaces(ctx context.Context, ipv4s []string, ipv6s []string, macs []string) ([]entity.NetworkInterface, error)
+
+	// Transaction runs fn with a repository bound to a single database transaction,
+	// committed when fn returns nil and rolled back otherwise
+	Transaction(ctx context.Context, fn func(repo Repository) error) error
+}
+
+// ImportJobRepository defines the interface for import job persistence
+type ImportJobRepository interface {
+	CreateJob(ctx context.Context, job *entity.ImportJob) error
+	// GetJob returns the job without its file data and report, nil when the job does not exist
+	GetJob(ctx context.Context, id string) (*entity.ImportJob, error)
+	// GetJobData returns the uploaded file of a job, nil when the job or its file does not exist
+	GetJobData(ctx context.Context, id string) ([]byte, error)
+	// GetJobReport returns the annotated report of a job, nil when the job or its report does not exist
+	GetJobReport(ctx context.Context, id string) ([]byte, error)
+	// UpdateJob saves the job's status, progress, result, file data and report
+	UpdateJob(ctx context.Context, job *entity.ImportJob) error
+	// ListJobsByStatus returns the jobs in status, oldest first
+	ListJobsByStatus(ctx context.Context, status entity.ImportJobStatus) ([]entity.ImportJob, error)
+}
+
+// GroupRepository defines the interface for server group persistence
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, group *entity.ServerGroup) error
+	// GetGroup returns the group with its member IDs, nil when it does not exist
+	GetGroup(ctx context.Context, id string) (*entity.ServerGroup, error)
+	// GetGroupByName returns the group with its member IDs, nil when it does not exist
+	GetGroupByName(ctx context.Context, name string) (*entity.ServerGroup, error)
+	// ListGroups returns every group with its member IDs, sorted by name
+	ListGroups(ctx context.Context) ([]entity.ServerGroup, error)
+	// UpdateGroup saves the group's name and description
+	UpdateGroup(ctx context.Context, group *entity.ServerGroup) error
+	DeleteGroup(ctx context.Context, id string) error
+
+	// AddMembers adds servers to a group, servers already in it are left alone
+	AddMembers(ctx context.Context, groupID string, serverIDs []string) error
+	RemoveMembers(ctx context.Context, groupID string, serverIDs []string) error
+
+	// Transaction runs fn with a repository bound to a single database transaction,
+	// committed when fn returns nil and rolled back otherwise
+	Transaction(ctx context.Context, fn func(groups GroupRepository) error) error
 }
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// ErrInvalidSearch is returned when a search request cannot be run
+var ErrInvalidSearch = errors.New("invalid search")
+
+const (
+	// DefaultSearchLimit is the number of results returned when no limit is requested
+	DefaultSearchLimit = 20
+	// MaxSearchLimit is the largest number of results a search returns
+	MaxSearchLimit = 100
+	// maxSearchLength is the longest search text accepted, in characters
+	maxSearchLength = 200
+
+	// fuzzyHighlightSimilarity is the trigram similarity from which a word is highlighted as a typo of a term
+	fuzzyHighlightSimilarity = 0.4
+)
+
+// SearchServers finds the servers whose name, ID, addresses or metadata match req.Query,
+// best matches first. Words match by prefix and tolerate typos, and the matched parts of
+// each field are returned highlighted.
+func (uc *ServerUsecase) SearchServers(ctx context.Context, req SearchServerRequest) (*SearchServerResponse, error) {
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if len([]rune(query)) > maxSearchLength {
+		return nil, fmt.Errorf("%w: q is longer than %d characters", ErrInvalidSearch, maxSearchLength)
+	}
+
+	limit := req.Limit
+	switch {
+	case limit < 0:
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidSearch)
+	case limit == 0:
+		limit = DefaultSearchLimit
+	case limit > MaxSearchLimit:
+		limit = MaxSearchLimit
+	}
+
+	results, err := uc.serverRepo.Search(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search servers: %w", err)
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	for i := range results {
+		results[i].Highlights = highlightServer(results[i].Server, terms)
+	}
+
+	return &SearchServerResponse{Query: query, Results: results}, nil
+}
+
+// highlightServer returns the fields of server matching any of terms, HTML-escaped,
+// with the matches wrapped in <mark> tags
+func highlightServer(server entity.Server, terms []string) map[string]string {
+	fields := map[string]string{
+		"id":          server.ID,
+		"name":        server.Name,
+		"ipv4":        server.IPv4,
+		"ipv6":        server.IPv6,
+		"description": server.Description,
+		"environment": server.Environment,
+		"location":    server.Location,
+		"owner":       server.Owner,
+		"labels":      server.Labels.String(),
+		"interfaces":  server.Interfaces.String(),
+	}
+
+	highlights := make(map[string]string)
+	for field, value := range fields {
+		if highlighted, ok := highlightMatches(value, terms); ok {
+			highlights[field] = highlighted
+		}
+	}
+	return highlights
+}
+
+// highlightMatches wraps the occurrences of terms in value, and the words of value similar
+// enough to a term to be a typo of it, in <mark> tags. It reports whether anything matched.
+func highlightMatches(value string, terms []string) (string, bool) {
+	runes := []rune(value)
+	lower := []rune(strings.ToLower(value))
+	if len(lower) != len(runes) {
+		lower = runes // Lower-casing changed the length, match case-sensitively
+	}
+
+	var spans [][2]int // [start, end) rune offsets
+	for _, term := range terms {
+		termRunes := []rune(term)
+		for i := 0; i+len(termRunes) <= len(lower); i++ {
+			if string(lower[i:i+len(termRunes)]) == term {
+				spans = append(spans, [2]int{i, i + len(termRunes)})
+			}
+		}
+	}
+	for _, word := range searchWords(lower) {
+		for _, term := range terms {
+			if trigramSimilarity(string(lower[word[0]:word[1]]), term) >= fuzzyHighlightSimilarity {
+				spans = append(spans, word)
+				break
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return "", false
+	}
+
+	// Merge overlapping spans
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span[0] <= last[1] {
+			last[1] = max(last[1], span[1])
+			continue
+		}
+		merged = append(merged, span)
+	}
+
+	var b strings.Builder
+	position := 0
+	for _, span := range merged {
+		b.WriteString(html.EscapeString(string(runes[position:span[0]])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[span[0]:span[1]])))
+		b.WriteString("</mark>")
+		position = span[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[position:])))
+	return b.String(), true
+}
+
+// searchWords returns the [start, end) offsets of the runs of letters and digits in s
+func searchWords(s []rune) [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range s {
+		alphanumeric := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case alphanumeric && start < 0:
+			start = i
+		case !alphanumeric && start >= 0:
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(s)})
+	}
+	return words
+}
+
+// trigramSimilarity compares two words the way pg_trgm does: the share of
+// trigrams, of the words padded with spaces, that they have in common
+func trigramSimilarity(a, b string) float64 {
+	trigramsA, trigramsB := trigrams(a), trigrams(b)
+	if len(trigramsA) == 0 || len(trigramsB) == 0 {
+		return 0
+	}
+	shared := 0
+	for trigram := range trigramsA {
+		if trigramsB[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(trigramsA)+len(trigramsB)-shared)
+}
+
+func trigrams(word string) map[string]bool {
+	padded := []rune("  " + word + " ")
+	set := make(map[string]bool, len(padded))
+	for i := 0; i+3 <= len(padded); i++ {
+		set[string(padded[i:i+3])] = true
+	}
+	return set
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+// LabelOperator is the comparison of a label requirement
+type LabelOperator string
+
+const (
+	LabelEquals       LabelOperator = "="
+	LabelNotEquals    LabelOperator = "!="
+	LabelIn           LabelOperator = "in"
+	LabelNotIn        LabelOperator = "notin"
+	LabelExists       LabelOperator = "exists"
+	LabelDoesNotExist LabelOperator = "!"
+)
+
+// LabelRequirement is one condition of a label selector
+type LabelRequirement struct {
+	Key      string
+	Operator LabelOperator
+	Values   []string // one value for = and !=, none for exists and !
+}
+
+// LabelSelector selects servers whose labels meet every requirement
+type LabelSelector []LabelRequirement
+
+var labelSetPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// ParseLabelSelector parses comma-separated requirements such as
+//
+//	env=prod,region in (us-east,eu-central),tier!=cache,!deprecated,team
+//
+// Keys without an operator require the label to exist, keys prefixed with ! require it to be absent.
+// A server without a label matches != and notin requirements on it.
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	var selector LabelSelector
+	for _, part := range splitSelector(s) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		requirement, err := parseLabelRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, requirement)
+	}
+	return selector, nil
+}
+
+// splitSelector splits s on the commas that are not inside parentheses
+func splitSelector(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func parseLabelRequirement(part string) (LabelRequirement, error) {
+	var requirement LabelRequirement
+
+	if match := labelSetPattern.FindStringSubmatch(part); match != nil {
+		requirement = LabelRequirement{Key: match[1], Operator: LabelOperator(match[2])}
+		if strings.TrimSpace(match[3]) == "" {
+			return LabelRequirement{}, fmt.Errorf("invalid label selector %q: empty value list", part)
+		}
+		for _, value := range strings.Split(match[3], ",") {
+			requirement.Values = append(requirement.Values, strings.TrimSpace(value))
+		}
+	} else if key, value, ok := strings.Cut(part, "!="); ok {
+		requirement = LabelRequirement{Key: strings.TrimSpace(key), Operator: LabelNotEquals, Values: []string{strings.TrimSpace(value)}}
+	} else if key, value, ok := strings.Cut(part, "="); ok {
+		value = strings.TrimPrefix(value, "=") // == is accepted as well
+		requirement = LabelRequirement{Key: strings.TrimSpace(key), Operator: LabelEquals, Values: []string{strings.TrimSpace(value)}}
+	} else if key, ok := strings.CutPrefix(part, "!"); ok {
+		requirement = LabelRequirement{Key: strings.TrimSpace(key), Operator: LabelDoesNotExist}
+	} else {
+		requirement = LabelRequirement{Key: part, Operator: LabelExists}
+	}
+
+	if !entity.ValidLabelKey(requirement.Key) {
+		return LabelRequirement{}, fmt.Errorf("invalid label selector %q: invalid key %q", part, requirement.Key)
+	}
+	for _, value := range requirement.Values {
+		if !entity.ValidLabelValue(value) {
+			return LabelRequirement{}, fmt.Errorf("invalid label selector %q: invalid value %q", part, value)
+		}
+	}
+	return requirement, nil
+}
+
+// Matches reports whether labels meet every requirement of the selector
+func (s LabelSelector) Matches(labels entity.Labels) bool {
+	for _, requirement := range s {
+		value, exists := labels[requirement.Key]
+		var matched bool
+		switch requirement.Operator {
+		case LabelEquals, LabelIn:
+			matched = exists && slices.Contains(requirement.Values, value)
+		case LabelNotEquals, LabelNotIn:
+			matched = !exists || !slices.Contains(requirement.Values, value)
+		case LabelExists:
+			matched = exists
+		case LabelDoesNotExist:
+			matched = !exists
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
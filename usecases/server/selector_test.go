@@ -0,0 +1,113 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lits-06/vcs-sms/entity"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    LabelSelector
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: nil},
+		{name: "only separators", input: " , ,", want: nil},
+		{
+			name:  "equals",
+			input: "env=prod",
+			want:  LabelSelector{{Key: "env", Operator: LabelEquals, Values: []string{"prod"}}},
+		},
+		{
+			name:  "double equals",
+			input: "env==prod",
+			want:  LabelSelector{{Key: "env", Operator: LabelEquals, Values: []string{"prod"}}},
+		},
+		{
+			name:  "not equals",
+			input: "tier != cache",
+			want:  LabelSelector{{Key: "tier", Operator: LabelNotEquals, Values: []string{"cache"}}},
+		},
+		{
+			name:  "in",
+			input: "region in (us-east, eu-central)",
+			want:  LabelSelector{{Key: "region", Operator: LabelIn, Values: []string{"us-east", "eu-central"}}},
+		},
+		{
+			name:  "notin",
+			input: "region notin (us-east)",
+			want:  LabelSelector{{Key: "region", Operator: LabelNotIn, Values: []string{"us-east"}}},
+		},
+		{
+			name:  "exists",
+			input: "team",
+			want:  LabelSelector{{Key: "team", Operator: LabelExists}},
+		},
+		{
+			name:  "does not exist",
+			input: "!deprecated",
+			want:  LabelSelector{{Key: "deprecated", Operator: LabelDoesNotExist}},
+		},
+		{
+			name:  "combined",
+			input: "env=prod,region in (us-east,eu-central),!deprecated",
+			want: LabelSelector{
+				{Key: "env", Operator: LabelEquals, Values: []string{"prod"}},
+				{Key: "region", Operator: LabelIn, Values: []string{"us-east", "eu-central"}},
+				{Key: "deprecated", Operator: LabelDoesNotExist},
+			},
+		},
+		{name: "empty in list", input: "region in ()", wantErr: true},
+		{name: "blank notin list", input: "region notin ( )", wantErr: true},
+		{name: "invalid key", input: "bad key=1", wantErr: true},
+		{name: "missing key", input: "=prod", wantErr: true},
+		{name: "invalid value", input: "env=a b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLabelSelector(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLabelSelector(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLabelSelector(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	labels := entity.Labels{"env": "prod", "region": "us-east"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"env=prod", true},
+		{"env=staging", false},
+		{"env!=staging", true},
+		{"team!=core", true}, // a missing label matches !=
+		{"region in (us-east,eu-central)", true},
+		{"region notin (us-east)", false},
+		{"team notin (core)", true},
+		{"env", true},
+		{"team", false},
+		{"!team", true},
+		{"!env", false},
+		{"env=prod,!team,region in (us-east)", true},
+	}
+
+	for _, tt := range tests {
+		selector, err := ParseLabelSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseLabelSelector(%q) error = %v", tt.selector, err)
+		}
+		if got := selector.Matches(labels); got != tt.want {
+			t.Errorf("%q.Matches(%v) = %v, want %v", tt.selector, labels, got, tt.want)
+		}
+	}
+}
@@ -3,23 +3,21 @@ package server
 import (
 	"context"
 	"fmt"
-	"mime/multipart"
-	"path/filepath"
+	"io"
 	"time"
 
 	"github.com/lits-06/vcs-sms/entity"
-	"github.com/xuri/excelize/v2"
 )
 
 type ServerUsecase struct {
-	serverRepo     Repository
-	serverProvider Provider
+	serverRepo Repository
+	providers  *ProviderRegistry
 }
 
-func NewServerUsecase(serverRepo Repository, serverProvider Provider) *ServerUsecase {
+func NewServerUsecase(serverRepo Repository, providers *ProviderRegistry) *ServerUsecase {
 	return &ServerUsecase{
-		serverRepo:     serverRepo,
-		serverProvider: serverProvider,
+		serverRepo: serverRepo,
+		providers:  providers,
 	}
 }
 
@@ -42,6 +40,47 @@ func (uc *ServerUsecase) CreateServer(ctx context.Context, req CreateServerReque
 		return nil, fmt.Errorf("server with name %s already exists", req.Name)
 	}
 
+	// Check that no other server uses its addresses
+	interfaces, err := resolveInterfaces(req.IPv4, req.IPv6, req.Interfaces)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network interfaces: %w", err)
+	}
+	if err := uc.checkAddresses(ctx, req.ID, interfaces); err != nil {
+		return nil, err
+	}
+	req.Interfaces = interfaces
+
+	server, err := uc.provisionServer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save to database, releasing the provider's resources such as the port when that fails
+	err = uc.serverRepo.Create(ctx, server)
+	if err != nil {
+		uc.deprovisionServer(ctx, server)
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+
+	return server, nil
+}
+
+// provisionServer builds the server entity for req and creates it in its provider. It does not save it.
+// req.Interfaces must already be resolved, see resolveInterfaces.
+func (uc *ServerUsecase) provisionServer(ctx context.Context, req CreateServerRequest) (*entity.Server, error) {
+	provider, err := uc.providers.Get(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Profile != nil {
+		if err := req.Profile.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid profile: %w", err)
+		}
+	}
+	if err := req.Labels.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid labels: %w", err)
+	}
 	// Create server entity
 	server := &entity.Server{
 		ID:        req.ID,
@@ -49,18 +88,29 @@ func (uc *ServerUsecase) CreateServer(ctx context.Context, req CreateServerReque
 		Status:    req.Status,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		IPv4:      req.IPv4,
+		Provider:  uc.providers.Resolve(req.Provider),
+		Profile:   req.Profile,
+
+		Description: req.Description,
+		Environment: req.Environment,
+		Location:    req.Location,
+		Owner:       req.Owner,
+		Labels:      req.Labels,
 	}
+	server.SetInterfaces(req.Interfaces)
 
-	err = uc.serverProvider.CreateServer(ctx, server)
+	err = provider.CreateServer(ctx, server)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create server: %w", err)
 	}
 
-	// Save to database
-	err = uc.serverRepo.Create(ctx, server)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create server: %w", err)
+	// Status of unmanaged servers is whatever the health check reports
+	if !uc.providers.IsManaged(server.Provider) {
+		status, err := provider.GetServerStatus(ctx, server.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check server status: %w", err)
+		}
+		server.Status = status
 	}
 
 	return server, nil
@@ -68,8 +118,31 @@ func (uc *ServerUsecase) CreateServer(ctx context.Context, req CreateServerReque
 
 // ViewServers retrieves servers with filtering, sorting, and pagination
 func (uc *ServerUsecase) ViewServer(ctx context.Context, req QueryServerRequest) (*QueryServerResponse, error) {
-	// Get servers from repository
-	servers, total, err := uc.serverRepo.List(ctx, req.Filter, req.Sort, req.Pagination)
+	if err := req.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	if err := req.Sort.Validate(); err != nil {
+		return nil, err
+	}
+	fields, err := ParseFields(req.Fields)
+	if err != nil {
+		return nil, err
+	}
+	includes, err := ParseIncludes(req.Include)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIncludePage(includes, req.Pagination); err != nil {
+		return nil, err
+	}
+
+	// Get servers from repository, with only the requested columns.
+	// Included data comes from the provider, so it is loaded too.
+	columns := fields
+	if len(fields) > 0 && len(includes) > 0 && !containsString(fields, "provider") {
+		columns = append(fields[:len(fields):len(fields)], "provider")
+	}
+	servers, total, err := uc.serverRepo.ListFields(ctx, req.Filter, req.Sort, req.Pagination, columns)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list servers: %w", err)
 	}
@@ -79,6 +152,10 @@ func (uc *ServerUsecase) ViewServer(ctx context.Context, req QueryServerRequest)
 		Servers: servers,
 		Total:   total,
 	}
+	if len(fields) > 0 || len(includes) > 0 {
+		response.Servers = nil
+		response.Views = uc.viewServers(ctx, *servers, fields, includes)
+	}
 
 	return response, nil
 }
@@ -102,23 +179,69 @@ func (uc *ServerUsecase) UpdateServer(ctx context.Context, req UpdateServerReque
 		if existingServer != nil && existingServer.ID != req.ID {
 			return fmt.Errorf("server with name %s already exists", req.Name)
 		}
+	}
+
+	return uc.applyUpdate(ctx, server, req)
+}
 
+// applyUpdate applies the non-empty fields of req to server, in its provider and in the database.
+// The new name must already be known to be free.
+func (uc *ServerUsecase) applyUpdate(ctx context.Context, server *entity.Server, req UpdateServerRequest) error {
+	if req.Name != "" {
 		server.Name = req.Name
 	}
 
+	if req.Status != "" && req.Status != server.Status && !uc.providers.IsManaged(server.Provider) {
+		return fmt.Errorf("cannot change status of server %s: %w", req.ID, ErrUnmanagedServer)
+	}
+
 	if req.Status != "" {
 		server.Status = req.Status
 	}
 
-	if req.IPv4 != "" {
-		server.IPv4 = req.IPv4
+	if req.Interfaces != nil || req.IPv4 != "" || req.IPv6 != "" {
+		interfaces, err := updateInterfaces(server, req)
+		if err != nil {
+			return fmt.Errorf("invalid network interfaces: %w", err)
+		}
+		if err := uc.checkAddresses(ctx, server.ID, interfaces); err != nil {
+			return err
+		}
+		server.SetInterfaces(interfaces)
 	}
 
-	if req.Status != "" {
-		server.Status = req.Status
+	if req.Profile != nil {
+		if err := req.Profile.Validate(); err != nil {
+			return fmt.Errorf("invalid profile: %w", err)
+		}
+		server.Profile = req.Profile
 	}
 
-	err = uc.serverProvider.UpdateServer(ctx, server)
+	if req.Description != nil {
+		server.Description = *req.Description
+	}
+	if req.Environment != nil {
+		server.Environment = *req.Environment
+	}
+	if req.Location != nil {
+		server.Location = *req.Location
+	}
+	if req.Owner != nil {
+		server.Owner = *req.Owner
+	}
+	if req.Labels != nil {
+		if err := req.Labels.Validate(); err != nil {
+			return fmt.Errorf("invalid labels: %w", err)
+		}
+		server.Labels = req.Labels
+	}
+
+	provider, err := uc.providers.Get(server.Provider)
+	if err != nil {
+		return err
+	}
+
+	err = provider.UpdateServer(ctx, server)
 	if err != nil {
 		return fmt.Errorf("failed to update server in provider: %w", err)
 	}
@@ -138,15 +261,20 @@ func (uc *ServerUsecase) DeleteServer(ctx context.Context, serverID string) erro
 	}
 
 	// Check if server exists
-	exist, err := uc.serverRepo.ExistsWithID(ctx, serverID)
+	server, err := uc.serverRepo.GetByID(ctx, serverID)
 	if err != nil {
 		return fmt.Errorf("server not found: %w", err)
 	}
-	if !exist {
+	if server == nil {
 		return fmt.Errorf("server with ID %s does not exist", serverID)
 	}
 
-	err = uc.serverProvider.DeleteServer(ctx, serverID)
+	provider, err := uc.providers.Get(server.Provider)
+	if err != nil {
+		return err
+	}
+
+	err = provider.DeleteServer(ctx, serverID)
 	if err != nil {
 		return fmt.Errorf("failed to delete server from provider: %w", err)
 	}
@@ -160,144 +288,99 @@ func (uc *ServerUsecase) DeleteServer(ctx context.Context, serverID string) erro
 	return nil
 }
 
-func (uc *ServerUsecase) ImportServersFromExcel(ctx context.Context, file multipart.File) (*ImportRespose, error) {
-	// Open Excel file
-	f, err := excelize.OpenReader(file)
+// ImportServers creates the servers listed in file, which is read in format.
+// Rows of existing servers are handled according to opts.Mode.
+// In dry-run mode every row is validated and the outcome reported without changing anything.
+// With opts.Report, the result carries a copy of the file annotated with the outcome of each row.
+func (uc *ServerUsecase) ImportServers(ctx context.Context, file io.Reader, format ImportFormat, opts ImportOptions) (*ImportRespose, error) {
+	mode, err := ParseImportMode(string(opts.Mode))
 	if err != nil {
-		return nil, fmt.Errorf("failed to open Excel file: %w", err)
-	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Printf("Error closing Excel file: %v\n", err)
-		}
-	}()
-
-	sheets := f.GetSheetList()
-	if len(sheets) == 0 {
-		return nil, fmt.Errorf("Excel file must contain at least one sheet")
+		return nil, err
 	}
 
-	// Get all rows from Sheet1
-	rows, err := f.GetRows(sheets[0])
+	importer, err := NewImporter(format)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get rows from Excel file: %w", err)
-	}
-
-	if len(rows) < 2 {
-		return nil, fmt.Errorf("Excel file must contain at least headers and one data row")
+		return nil, err
 	}
 
-	result := &ImportRespose{
-		SuccessServers: make([]string, 0),
-		FailureServers: make([]string, 0),
+	sheet, err := importer.ReadSheet(file)
+	if err != nil {
+		return nil, err
 	}
 
-	// Skip header row and process data rows
-	for _, row := range rows[1:] {
-		if len(row) < 3 { // At least ID, Name, IPv4 required
-			continue
-		}
-
-		serverID := row[0]
-		serverName := row[1]
-		serverIPv4 := row[2]
-
-		if serverID == "" || serverName == "" || serverIPv4 == "" {
-			result.FailureCount++
-			result.FailureServers = append(result.FailureServers, fmt.Sprintf("%s:%s - missing required fields", serverID, serverName))
-			continue
-		}
+	opts.Mode = mode
+	return uc.importSheet(ctx, sheet, opts, nil)
+}
 
-		// Check if server with same ID already exists
-		existID, err := uc.serverRepo.ExistsWithID(ctx, serverID)
-		if err != nil {
-			result.FailureCount++
-			result.FailureServers = append(result.FailureServers, fmt.Sprintf("%s:%s - failed to check ID existence", serverID, serverName))
-			continue
-		}
-		if existID {
-			result.FailureCount++
-			result.FailureServers = append(result.FailureServers, fmt.Sprintf("%s:%s - ID already exists", serverID, serverName))
-			continue
-		}
+// exportBatchSize is the number of servers fetched from the repository per query while exporting
+const exportBatchSize = 1000
 
-		// Check if server with same name already exists
-		existName, err := uc.serverRepo.ExistsWithName(ctx, serverName)
-		if err != nil {
-			result.FailureCount++
-			result.FailureServers = append(result.FailureServers, fmt.Sprintf("%s:%s - failed to check name existence", serverID, serverName))
-			continue
-		}
-		if existName {
-			result.FailureCount++
-			result.FailureServers = append(result.FailureServers, fmt.Sprintf("%s:%s - name already exists", serverID, serverName))
-			continue
-		}
-
-		// Create server request
-		req := CreateServerRequest{
-			ID:   serverID,
-			Name: serverName,
-			IPv4: serverIPv4,
-		}
+// ExportServers writes the servers matching req to w in req.Format, with the columns,
+// time zone and header locale requested in req
+func (uc *ServerUsecase) ExportServers(ctx context.Context, req ExportServerRequest, w io.Writer) error {
+	format, err := ParseExportFormat(string(req.Format))
+	if err != nil {
+		return err
+	}
 
-		// Create server
-		_, err = uc.CreateServer(ctx, req)
-		if err != nil {
-			result.FailureCount++
-			result.FailureServers = append(result.FailureServers, fmt.Sprintf("%s:%s - %v", serverID, serverName, err))
-			continue
-		}
+	if err := req.Filter.Validate(); err != nil {
+		return err
+	}
+	if err := req.Sort.Validate(); err != nil {
+		return err
+	}
 
-		result.SuccessCount++
-		result.SuccessServers = append(result.SuccessServers, fmt.Sprintf("%s:%s", serverID, serverName))
+	layout, err := NewExportLayout(req)
+	if err != nil {
+		return err
 	}
 
-	return result, nil
-}
+	exporter, err := NewExporter(format, w, layout)
+	if err != nil {
+		return err
+	}
+	defer exporter.Release()
 
-func (uc *ServerUsecase) ExportServersToExcel(ctx context.Context, req QueryServerRequest) error {
-	// Get servers from repository
-	servers, _, err := uc.serverRepo.List(ctx, req.Filter, req.Sort, req.Pagination)
+	err = uc.eachServer(ctx, req.QueryServerRequest, exporter.WriteServer)
 	if err != nil {
-		return fmt.Errorf("failed to list servers: %w", err)
+		return err
 	}
 
-	// Create Excel file
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Printf("Error closing Excel file: %v\n", err)
-		}
-	}()
+	return exporter.Close()
+}
 
-	// Set headers
-	headers := []string{"ID", "Name", "IPv4", "Status", "Created At", "Updated At"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue("Sheet1", cell, header)
+// eachServer calls fn for every server matching req, fetching them from the
+// repository in batches. Pagination in req limits the range that is visited.
+func (uc *ServerUsecase) eachServer(ctx context.Context, req QueryServerRequest, fn func(server entity.Server) error) error {
+	offset := req.Pagination.From
+	end := -1 // no upper bound
+	if req.Pagination.To > 0 && req.Pagination.To >= req.Pagination.From {
+		end = req.Pagination.To
 	}
 
-	// Add server data
-	for i, server := range *servers {
-		row := i + 2 // Start from row 2 (after headers)
-		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", row), server.ID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", row), server.Name)
-		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", row), server.IPv4)
-		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", row), string(server.Status))
-		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", row), server.CreatedAt.Format("2006-01-02 15:04:05"))
-		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", row), server.UpdatedAt.Format("2006-01-02 15:04:05"))
-	}
+	for {
+		to := offset + exportBatchSize
+		if end >= 0 && to > end {
+			to = end
+		}
+		if to <= offset {
+			return nil
+		}
 
-	// Create filename with timestamp
-	filename := fmt.Sprintf("servers_export_%s.xlsx", time.Now().Format("20060102_150405"))
+		servers, _, err := uc.serverRepo.List(ctx, req.Filter, req.Sort, ServerPagination{From: offset, To: to})
+		if err != nil {
+			return fmt.Errorf("failed to list servers: %w", err)
+		}
 
-	path := filepath.Join("../../exports", filename)
+		for _, server := range *servers {
+			if err := fn(server); err != nil {
+				return err
+			}
+		}
 
-	// Save the file
-	if err := f.SaveAs(path); err != nil {
-		return fmt.Errorf("failed to save Excel file: %w", err)
+		if len(*servers) < to-offset {
+			return nil
+		}
+		offset = to
 	}
-
-	return nil
 }
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSort is returned when the requested sort order cannot be applied
+var ErrInvalidSort = errors.New("invalid sort")
+
+// maxSortKeys is the largest number of fields a list can be sorted by
+const maxSortKeys = 8
+
+// DefaultSortField is the field servers are sorted by when no sort is requested
+const DefaultSortField = "created_at"
+
+// sortFields are the fields servers can be sorted by. Names sort in natural order,
+// so web-2 comes before web-10, and addresses in numeric order.
+var sortFields = map[string]bool{
+	"id":          true,
+	"name":        true,
+	"status":      true,
+	"ipv4":        true,
+	"ipv6":        true,
+	"provider":    true,
+	"environment": true,
+	"location":    true,
+	"owner":       true,
+	"created_at":  true,
+	"updated_at":  true,
+}
+
+// SortKey is a field to sort by and its direction
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Validate checks that the sort can be parsed
+func (s ServerSort) Validate() error {
+	_, err := ParseSort(s)
+	return err
+}
+
+// ParseSort parses the comma-separated fields of s.Sort, each optionally prefixed with - for
+// descending or + for ascending order. Fields without a sign follow s.Order. The ID is appended
+// as a final ascending key, unless already listed, so that pages are stable when other fields tie.
+func ParseSort(s ServerSort) ([]SortKey, error) {
+	defaultDesc := false
+	switch SortOrder(strings.ToLower(string(s.Order))) {
+	case "", SortAsc:
+	case SortDesc:
+		defaultDesc = true
+	default:
+		return nil, fmt.Errorf("%w: unknown order %q (use asc or desc)", ErrInvalidSort, s.Order)
+	}
+
+	fields := s.Sort
+	if strings.TrimSpace(fields) == "" {
+		fields = DefaultSortField
+	}
+
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(fields, ",") {
+		term = strings.TrimSpace(term)
+		key := SortKey{Desc: defaultDesc}
+		switch {
+		case strings.HasPrefix(term, "-"):
+			key.Desc = true
+			term = term[1:]
+		case strings.HasPrefix(term, "+"):
+			key.Desc = false
+			term = term[1:]
+		}
+		key.Field = strings.ToLower(strings.TrimSpace(term))
+
+		if key.Field == "" {
+			return nil, fmt.Errorf("%w: empty sort field in %q", ErrInvalidSort, s.Sort)
+		}
+		if !sortFields[key.Field] {
+			return nil, fmt.Errorf("%w: unknown sort field %q (use %s)", ErrInvalidSort, key.Field, strings.Join(sortFieldNames(), ", "))
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("%w: %q is sorted by more than once", ErrInvalidSort, key.Field)
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	if len(keys) > maxSortKeys {
+		return nil, fmt.Errorf("%w: at most %d sort fields are allowed", ErrInvalidSort, maxSortKeys)
+	}
+
+	if !seen["id"] {
+		keys = append(keys, SortKey{Field: "id"})
+	}
+	return keys, nil
+}
+
+func sortFieldNames() []string {
+	names := make([]string, 0, len(sortFields))
+	for name := range sortFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    ServerSort
+		want    []SortKey
+		wantErr bool
+	}{
+		{
+			name: "default field",
+			sort: ServerSort{},
+			want: []SortKey{{Field: "created_at"}, {Field: "id"}},
+		},
+		{
+			name: "default field follows order",
+			sort: ServerSort{Order: SortDesc},
+			want: []SortKey{{Field: "created_at", Desc: true}, {Field: "id"}},
+		},
+		{
+			name: "signs",
+			sort: ServerSort{Sort: "-status,+name,ipv4"},
+			want: []SortKey{{Field: "status", Desc: true}, {Field: "name"}, {Field: "ipv4"}, {Field: "id"}},
+		},
+		{
+			name: "signs override order",
+			sort: ServerSort{Sort: "+name, location", Order: "DESC"},
+			want: []SortKey{{Field: "name"}, {Field: "location", Desc: true}, {Field: "id"}},
+		},
+		{
+			name: "fields are case insensitive",
+			sort: ServerSort{Sort: " Name "},
+			want: []SortKey{{Field: "name"}, {Field: "id"}},
+		},
+		{
+			name: "id already listed",
+			sort: ServerSort{Sort: "-id,name"},
+			want: []SortKey{{Field: "id", Desc: true}, {Field: "name"}},
+		},
+		{
+			name: "max sort keys",
+			sort: ServerSort{Sort: "name,status,ipv4,ipv6,provider,environment,location,owner"},
+			want: []SortKey{
+				{Field: "name"}, {Field: "status"}, {Field: "ipv4"}, {Field: "ipv6"},
+				{Field: "provider"}, {Field: "environment"}, {Field: "location"}, {Field: "owner"},
+				{Field: "id"},
+			},
+		},
+		{name: "too many sort keys", sort: ServerSort{Sort: "name,status,ipv4,ipv6,provider,environment,location,owner,updated_at"}, wantErr: true},
+		{name: "duplicate field", sort: ServerSort{Sort: "name,-name"}, wantErr: true},
+		{name: "unknown field", sort: ServerSort{Sort: "labels"}, wantErr: true},
+		{name: "empty field", sort: ServerSort{Sort: "name,,status"}, wantErr: true},
+		{name: "sign without field", sort: ServerSort{Sort: "-"}, wantErr: true},
+		{name: "unknown order", sort: ServerSort{Order: "up"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.sort)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSort) {
+					t.Fatalf("ParseSort(%+v) error = %v, want ErrInvalidSort", tt.sort, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSort(%+v) unexpected error: %v", tt.sort, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort(%+v) = %+v, want %+v", tt.sort, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ServerStats counts the servers matching req.Filter by status, environment, subnet and label,
+// and histograms their creation per day and per week
+func (uc *ServerUsecase) ServerStats(ctx context.Context, req ServerStatsRequest) (*ServerStats, error) {
+	if err := req.Filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	location := time.UTC
+	if req.TZ != "" {
+		loaded, err := time.LoadLocation(req.TZ)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidFilter, req.TZ)
+		}
+		location = loaded
+	}
+
+	stats, err := uc.serverRepo.Stats(ctx, req.Filter, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute server stats: %w", err)
+	}
+	return stats, nil
+}